@@ -284,7 +284,7 @@ func (s *BllSesn) openOnce() (bool, error) {
 	s.txvr = txvr
 
 	if err := s.connect(); err != nil {
-		return false, err
+		return s.cfg.ConnRetryAll, err
 	}
 
 	if err := s.exchangeMtu(); err != nil {
@@ -308,6 +308,13 @@ func (s *BllSesn) Open() error {
 	for i := 0; i < s.cfg.ConnTries; i++ {
 		var retry bool
 
+		if i > 0 {
+			delay := nmxutil.RetryDelay(s.cfg.ConnRetryDelay, i-1)
+			log.Debugf("Retrying BLE connection in %s (attempt %d of %d)",
+				delay, i+1, s.cfg.ConnTries)
+			time.Sleep(delay)
+		}
+
 		retry, err = s.openOnce()
 		if err != nil {
 			// Ensure the session is closed.
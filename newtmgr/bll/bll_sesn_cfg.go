@@ -31,21 +31,24 @@ import (
 )
 
 type BllSesnCfg struct {
-	MgmtProto    sesn.MgmtProto
-	AdvFilter    ble.AdvFilter
-	PreferredMtu uint16
-	ConnTimeout  time.Duration
-	ConnTries    int
-	WriteRsp     bool
-	TxFilter     nmcoap.TxMsgFilter
-	RxFilter     nmcoap.RxMsgFilter
+	MgmtProto      sesn.MgmtProto
+	AdvFilter      ble.AdvFilter
+	PreferredMtu   uint16
+	ConnTimeout    time.Duration
+	ConnTries      int
+	ConnRetryDelay time.Duration
+	ConnRetryAll   bool
+	WriteRsp       bool
+	TxFilter       nmcoap.TxMsgFilter
+	RxFilter       nmcoap.RxMsgFilter
 }
 
 func NewBllSesnCfg() BllSesnCfg {
 	return BllSesnCfg{
-		PreferredMtu: 512,
-		ConnTimeout:  10 * time.Second,
-		ConnTries:    3,
-		WriteRsp:     false,
+		PreferredMtu:   512,
+		ConnTimeout:    10 * time.Second,
+		ConnTries:      3,
+		ConnRetryDelay: 250 * time.Millisecond,
+		WriteRsp:       false,
 	}
 }
@@ -0,0 +1,139 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"mynewt.apache.org/newt/util"
+	"mynewt.apache.org/newtmgr/newtmgr/config"
+	"mynewt.apache.org/newtmgr/nmxact/bledefs"
+	"mynewt.apache.org/newtmgr/nmxact/nmble"
+	"mynewt.apache.org/newtmgr/nmxact/nmxutil"
+)
+
+var connScanDuration float64
+var connScanAll bool
+
+// Indicates whether an advertisement lists one of the management services.
+func advHasMgmtSvc(adv bledefs.BleAdvReport) bool {
+	for _, u := range adv.Fields.Uuids16 {
+		if u == bledefs.SecureSvcUuid {
+			return true
+		}
+	}
+
+	svcs := []string{bledefs.NmpPlainSvcUuid, bledefs.OmpUnsecSvcUuid}
+	for _, s := range svcs {
+		svc, err := bledefs.ParseUuid128(s)
+		if err != nil {
+			continue
+		}
+		for _, u := range adv.Fields.Uuids128 {
+			if u == svc {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func connScanRunCmd(cmd *cobra.Command, args []string) {
+	if connScanDuration <= 0 {
+		nmUsage(cmd, util.FmtNewtError("Invalid duration: %g",
+			connScanDuration))
+	}
+
+	cp, err := getConnProfile()
+	if err != nil {
+		nmUsage(nil, err)
+	}
+
+	x, err := GetXport()
+	if err != nil {
+		nmUsage(nil, err)
+	}
+	bx, ok := x.(*nmble.BleXport)
+	if !ok {
+		nmUsage(nil, util.FmtNewtError(
+			"Scanning requires a %s or %s connection; have %s",
+			config.ConnTypeToString(config.CONN_TYPE_BLE_PLAIN),
+			config.ConnTypeToString(config.CONN_TYPE_BLE_OIC),
+			config.ConnTypeToString(cp.Type)))
+	}
+
+	bc, err := config.ParseBleConnString(cp.ConnString)
+	if err != nil {
+		nmUsage(nil, err)
+	}
+
+	d := nmble.NewDiscoverer(nmble.DiscovererParams{
+		Bx:          bx,
+		OwnAddrType: bc.OwnAddrType,
+		Passive:     false,
+		Duration:    time.Duration(connScanDuration * float64(time.Second)),
+	})
+
+	ach, ech, err := d.Start()
+	if err != nil {
+		nmUsage(nil, util.ChildNewtError(err))
+	}
+
+	fmt.Printf("%-24s %5s  %s\n", "ADDRESS", "RSSI", "NAME")
+
+	// Each device is reported once, when it is first seen.
+	seen := map[string]bool{}
+	for {
+		select {
+		case adv, ok := <-ach:
+			if !ok {
+				ach = nil
+				continue
+			}
+			if !connScanAll && !advHasMgmtSvc(adv) {
+				continue
+			}
+
+			addr := adv.Sender.String()
+			if seen[addr] {
+				continue
+			}
+			seen[addr] = true
+
+			name := ""
+			if adv.Fields.Name != nil {
+				name = *adv.Fields.Name
+			}
+			fmt.Printf("%-24s %5d  %s\n", addr, adv.Rssi, name)
+
+		case err := <-ech:
+			if err != nil && !nmxutil.IsScanTmo(err) {
+				nmUsage(nil, util.ChildNewtError(err))
+			}
+
+			fmt.Printf("%d device(s) found\n", len(seen))
+			return
+		}
+	}
+}
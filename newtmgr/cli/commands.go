@@ -51,6 +51,17 @@ func Commands() *cobra.Command {
 			}
 			nmxutil.SetLogLevel(NewtmgrLogLevel)
 
+			if nmutil.ConnectDelay < 0 {
+				nmUsage(cmd, util.FmtNewtError(
+					"Invalid connect delay: %g", nmutil.ConnectDelay))
+			}
+
+			if fleetMode() {
+				fleetRun()
+			}
+
+			applyConnProfileTxOptions(cmd)
+
 			// Set cbgo log level if we're using macOS.
 			OSSpecificInit()
 		},
@@ -62,11 +73,19 @@ func Commands() *cobra.Command {
 	nmCmd.PersistentFlags().StringVarP(&nmutil.ConnProfile, "conn", "c", "",
 		"connection profile to use")
 
+	nmCmd.PersistentFlags().StringVar(&fleetConns, "conns", "",
+		"comma-separated connection profiles; run the command against each "+
+			"device concurrently")
+
+	nmCmd.PersistentFlags().BoolVar(&fleetAllConns, "all-conns", false,
+		"run the command against every connection profile concurrently")
+
 	nmCmd.PersistentFlags().Float64VarP(&nmutil.Timeout, "timeout", "t", 10.0,
 		"timeout in seconds (partial seconds allowed)")
 
 	nmCmd.PersistentFlags().IntVarP(&nmutil.Tries, "tries", "r", 1,
-		"total number of tries in case of timeout")
+		"total number of tries in case of timeout; commands that must not be "+
+			"applied twice (reset, crash, run test) are never retried")
 
 	nmCmd.PersistentFlags().StringVarP(&logLevelStr, "loglevel", "l", "info",
 		"log level to use")
@@ -77,9 +96,21 @@ func Commands() *cobra.Command {
 	nmCmd.PersistentFlags().BoolVar(&nmutil.BleWriteRsp, "write-rsp", false,
 		"Send BLE acked write requests instead of unacked write commands")
 
+	nmCmd.PersistentFlags().IntVar(&nmutil.ConnectRetries, "connect-retries",
+		-1, "Number of times to retry a failed BLE connection; when set, "+
+			"every connect failure is retried (default: transport-specific)")
+
+	nmCmd.PersistentFlags().Float64Var(&nmutil.ConnectDelay, "connect-delay",
+		0.25, "Delay in seconds before the first BLE connection retry; "+
+			"doubles with each retry")
+
 	nmCmd.PersistentFlags().StringVar(&nmutil.ConnType, "conntype", "",
 		"Connection type to use instead of using the profile's type")
 
+	nmCmd.PersistentFlags().StringVar(&nmutil.ConnProto, "proto", "",
+		"Management protocol framing to use instead of the profile's "+
+			"(smp or oic)")
+
 	nmCmd.PersistentFlags().StringVar(&nmutil.ConnString, "connstring", "",
 		"Connection key-value pairs to use instead of using the profile's "+
 			"connstring")
@@ -87,6 +118,14 @@ func Commands() *cobra.Command {
 	nmCmd.PersistentFlags().StringVar(&nmutil.ConnExtra, "connextra", "",
 		"Additional key-value pair to append to the connstring")
 
+	nmCmd.PersistentFlags().BoolVar(&nmutil.JsonOutput, "json", false,
+		"Print decoded responses of read commands as JSON; for res, "+
+			"also take the message body as JSON")
+
+	nmCmd.PersistentFlags().BoolVar(&nmxutil.DebugProtocol,
+		"debug-protocol", false,
+		"Log every management packet's header and decoded payload to stderr")
+
 	nmCmd.PersistentFlags().StringVar(&nmxutil.OmpRes, "ompres", "/omgr",
 		"Use this CoAP resource instead of /omgr")
 
@@ -113,6 +152,7 @@ func Commands() *cobra.Command {
 	nmCmd.AddCommand(mempoolStatCmd())
 	nmCmd.AddCommand(resetCmd())
 	nmCmd.AddCommand(runCmd())
+	nmCmd.AddCommand(scriptCmd())
 	nmCmd.AddCommand(statsCmd())
 	nmCmd.AddCommand(taskStatCmd())
 	nmCmd.AddCommand(configCmd())
@@ -20,19 +20,25 @@
 package cli
 
 import (
+	"encoding/hex"
 	"fmt"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/ugorji/go/codec"
 
 	"mynewt.apache.org/newt/util"
 	"mynewt.apache.org/newtmgr/newtmgr/bll"
 	"mynewt.apache.org/newtmgr/newtmgr/config"
 	"mynewt.apache.org/newtmgr/newtmgr/nmutil"
+	"mynewt.apache.org/newtmgr/nmxact/client"
 	"mynewt.apache.org/newtmgr/nmxact/mtech_lora"
 	"mynewt.apache.org/newtmgr/nmxact/nmble"
 	"mynewt.apache.org/newtmgr/nmxact/nmcoap"
 	"mynewt.apache.org/newtmgr/nmxact/nmserial"
+	"mynewt.apache.org/newtmgr/nmxact/nmxutil"
 	"mynewt.apache.org/newtmgr/nmxact/sesn"
+	"mynewt.apache.org/newtmgr/nmxact/tcp"
 	"mynewt.apache.org/newtmgr/nmxact/udp"
 	"mynewt.apache.org/newtmgr/nmxact/xport"
 )
@@ -47,6 +53,14 @@ var globalXportSet bool
 var globalTxFilter nmcoap.TxMsgFilter
 var globalRxFilter nmcoap.RxMsgFilter
 
+// Number of tries used for UDP connections when neither the command line nor
+// the connection profile specifies one.
+const udpDefaultTries = 3
+
+// Whether the number of tries was specified on the command line or by the
+// connection profile.
+var globalTriesSet bool
+
 func initConnProfile() error {
 	var p *config.ConnProfile
 
@@ -73,6 +87,15 @@ func initConnProfile() error {
 		p.Type = t
 	}
 
+	if nmutil.ConnProto != "" {
+		t, err := config.ConnTypeWithProto(p.Type, nmutil.ConnProto)
+		if err != nil {
+			return err
+		}
+
+		p.Type = t
+	}
+
 	if nmutil.ConnString != "" {
 		p.ConnString = nmutil.ConnString
 	}
@@ -88,12 +111,56 @@ func initConnProfile() error {
 		return util.FmtNewtError("No connection type specified")
 	}
 
+	applyUdpDefaultTries(p)
+
 	log.Debugf("Using connection profile: %v", p)
 	globalP = p
 
 	return nil
 }
 
+// Datagrams are lost silently; retry unless told otherwise.  This is decided
+// from the resolved profile, i.e., after --conntype and --proto have been
+// applied.
+func applyUdpDefaultTries(p *config.ConnProfile) {
+	if !globalTriesSet && (p.Type == config.CONN_TYPE_UDP_PLAIN ||
+		p.Type == config.CONN_TYPE_UDP_OIC) {
+
+		nmutil.Tries = udpDefaultTries
+	}
+}
+
+// Applies the transaction defaults stored in the selected connection profile.
+// Values explicitly specified on the command line take precedence.
+func applyConnProfileTxOptions(cmd *cobra.Command) {
+	globalTriesSet = cmd.Flags().Changed("tries")
+
+	// When several commands share a connection (e.g., a script), the
+	// profile is only resolved once.
+	if globalP != nil {
+		defer applyUdpDefaultTries(globalP)
+	}
+
+	if nmutil.ConnProfile == "" {
+		return
+	}
+
+	// A nonexistent profile is reported when the connection is set up.
+	cpm := config.GlobalConnProfileMgr()
+	p, err := cpm.GetConnProfile(nmutil.ConnProfile)
+	if err != nil {
+		return
+	}
+
+	if p.Timeout > 0 && !cmd.Flags().Changed("timeout") {
+		nmutil.Timeout = p.Timeout
+	}
+	if p.Tries > 0 && !globalTriesSet {
+		nmutil.Tries = p.Tries
+		globalTriesSet = true
+	}
+}
+
 func getConnProfile() (*config.ConnProfile, error) {
 	if globalP == nil {
 		if err := initConnProfile(); err != nil {
@@ -104,6 +171,22 @@ func getConnProfile() (*config.ConnProfile, error) {
 	return globalP, nil
 }
 
+// Verifies that the profile's MTU, if any, is within the limits of the
+// profile's transport.  A connstring mtu takes precedence over the profile's
+// and is checked when the connstring is parsed.
+func checkProfileMtu(cp *config.ConnProfile, maxMtu int) error {
+	if cp.Mtu <= 0 || config.ConnStringHasKey(cp.ConnString, "mtu") {
+		return nil
+	}
+
+	if err := config.CheckMtu(cp.Mtu, maxMtu); err != nil {
+		return util.FmtNewtError("connection profile %s: %s", cp.Name,
+			err.Error())
+	}
+
+	return nil
+}
+
 func GetXport() (xport.Xport, error) {
 	if globalXport != nil {
 		return globalXport, nil
@@ -120,6 +203,12 @@ func GetXport() (xport.Xport, error) {
 		if err != nil {
 			return nil, err
 		}
+		if err := checkProfileMtu(cp, 0); err != nil {
+			return nil, err
+		}
+		if cp.Mtu > 0 && !config.ConnStringHasKey(cp.ConnString, "mtu") {
+			sc.Mtu = cp.Mtu
+		}
 
 		globalXport = nmserial.NewSerialXport(sc)
 
@@ -149,6 +238,9 @@ func GetXport() (xport.Xport, error) {
 	case config.CONN_TYPE_UDP_PLAIN, config.CONN_TYPE_UDP_OIC:
 		globalXport = udp.NewUdpXport()
 
+	case config.CONN_TYPE_TCP_PLAIN:
+		globalXport = tcp.NewTcpXport()
+
 	case config.CONN_TYPE_MTECH_LORA_OIC:
 		cfg := mtech_lora.NewXportCfg()
 		globalXport = mtech_lora.NewLoraXport(cfg)
@@ -232,15 +324,30 @@ func buildSesnCfg() (sesn.SesnCfg, error) {
 
 	case config.CONN_TYPE_UDP_PLAIN:
 		sc.MgmtProto = sesn.MGMT_PROTO_NMP
-		sc.PeerSpec.Udp = cp.ConnString
-
-		return sc, nil
+		if err := checkProfileMtu(cp, udp.MAX_PACKET_SIZE); err != nil {
+			return sc, err
+		}
+		sc.Udp.Mtu = cp.Mtu
+		err := config.FillUdpSesnCfg(cp.ConnString, &sc)
+		return sc, err
 
 	case config.CONN_TYPE_UDP_OIC:
 		sc.MgmtProto = sesn.MGMT_PROTO_OMP
-		sc.PeerSpec.Udp = cp.ConnString
+		if err := checkProfileMtu(cp, udp.MAX_PACKET_SIZE); err != nil {
+			return sc, err
+		}
+		sc.Udp.Mtu = cp.Mtu
+		err := config.FillUdpSesnCfg(cp.ConnString, &sc)
+		return sc, err
 
-		return sc, nil
+	case config.CONN_TYPE_TCP_PLAIN:
+		sc.MgmtProto = sesn.MGMT_PROTO_NMP
+		if err := checkProfileMtu(cp, tcp.MAX_PACKET_SIZE); err != nil {
+			return sc, err
+		}
+		sc.Tcp.Mtu = cp.Mtu
+		err := config.FillTcpSesnCfg(cp.ConnString, &sc)
+		return sc, err
 
 	case config.CONN_TYPE_MTECH_LORA_OIC:
 		mc, err := config.ParseMtechLoraConnString(cp.ConnString)
@@ -342,6 +449,31 @@ func GetSesn() (sesn.Sesn, error) {
 	return globalSesn, nil
 }
 
+// Returns a client for the current session that applies the global
+// transaction options.
+func GetClient() (*client.Client, error) {
+	s, err := GetSesn()
+	if err != nil {
+		return nil, err
+	}
+
+	c := client.New(s)
+	c.TxOptions = nmutil.TxOptions()
+
+	return c, nil
+}
+
+// Reports a failed client operation.  A nonzero status from the device is
+// printed; any other error aborts the command.
+func clientErr(err error) {
+	if rcErr, ok := err.(*client.RcError); ok {
+		fmt.Printf("Error: %d\n", rcErr.Rc)
+		return
+	}
+
+	nmUsage(nil, util.ChildNewtError(err))
+}
+
 func GetSesnIfOpen() (sesn.Sesn, error) {
 	if globalSesn == nil {
 		return nil, fmt.Errorf("sesn not initailized")
@@ -350,6 +482,58 @@ func GetSesnIfOpen() (sesn.Sesn, error) {
 	return globalSesn, nil
 }
 
+// Replaces the byte strings in a decoded CBOR value with their hex encoding.
+func jsonHexBytes(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []byte:
+		return hex.EncodeToString(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = jsonHexBytes(v[i])
+		}
+		return v
+	case map[interface{}]interface{}:
+		for k, e := range v {
+			v[k] = jsonHexBytes(e)
+		}
+		return v
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = jsonHexBytes(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// Prints a decoded response as JSON.  Field names are taken from the codec
+// tags, so they match the names used by the management protocol.  Byte
+// strings (e.g., image hashes) are printed in hex, as in the table output.
+func printJson(rsp interface{}) {
+	// Round-trip through CBOR to get a generic value in which byte strings
+	// can be told apart from text.
+	cb, err := nmxutil.EncodeCbor(rsp)
+	if err != nil {
+		nmUsage(nil, util.ChildNewtError(err))
+	}
+	val, err := nmxutil.DecodeCbor(cb)
+	if err != nil {
+		nmUsage(nil, util.ChildNewtError(err))
+	}
+
+	h := new(codec.JsonHandle)
+	h.Indent = 4
+	h.Canonical = true
+
+	var b []byte
+	if err := codec.NewEncoderBytes(&b, h).Encode(jsonHexBytes(val)); err != nil {
+		nmUsage(nil, util.ChildNewtError(err))
+	}
+
+	fmt.Printf("%s\n", b)
+}
+
 func SetFilters(txFilter nmcoap.TxMsgFilter, rxFilter nmcoap.RxMsgFilter) {
 	globalTxFilter = txFilter
 	globalRxFilter = rxFilter
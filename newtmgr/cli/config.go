@@ -20,7 +20,15 @@
 package cli
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -41,7 +49,9 @@ func configRead(s sesn.Sesn, args []string) {
 	}
 
 	sres := res.(*xact.ConfigReadResult)
-	if sres.Rsp.Rc != 0 {
+	if nmutil.JsonOutput {
+		printJson(sres.Rsp)
+	} else if sres.Rsp.Rc != 0 {
 		fmt.Printf("Error: %d\n", sres.Rsp.Rc)
 	} else {
 		fmt.Printf("Value: %s\n", sres.Rsp.Val)
@@ -85,12 +95,204 @@ func configSave(s sesn.Sesn, args []string) {
 	}
 }
 
+var configSetFile string
+var configGetFile string
+var configBatchSave bool
+
+type configPair struct {
+	name string
+	val  string
+}
+
+// Reads the settings in a JSON file containing a single object.  Non-string
+// values are converted to their JSON text.
+func readConfigJson(filename string) ([]configPair, error) {
+	blob, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, util.ChildNewtError(err)
+	}
+
+	// Keep numbers as written; converting them to float64 would corrupt
+	// integers wider than 53 bits.
+	m := map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewReader(blob))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return nil, util.FmtNewtError("%s: %s", filename, err.Error())
+	}
+
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	pairs := make([]configPair, 0, len(names))
+	for _, name := range names {
+		var val string
+		switch v := m[name].(type) {
+		case string:
+			val = v
+		case json.Number:
+			val = v.String()
+		default:
+			b, _ := json.Marshal(v)
+			val = string(b)
+		}
+		pairs = append(pairs, configPair{name, val})
+	}
+
+	return pairs, nil
+}
+
+// Reads the settings in a file.  A file with a .json extension must contain
+// a JSON object; any other file contains one "name=value" line per setting.
+// Blank lines and lines starting with '#' are ignored.  If needVal is false,
+// a line may consist of a name alone.
+func readConfigFile(filename string, needVal bool) ([]configPair, error) {
+	if strings.ToLower(filepath.Ext(filename)) == ".json" {
+		return readConfigJson(filename)
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, util.ChildNewtError(err)
+	}
+	defer f.Close()
+
+	var pairs []configPair
+
+	scanner := bufio.NewScanner(f)
+	for num := 1; scanner.Scan(); num++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		name := strings.TrimSpace(kv[0])
+		if name == "" || (needVal && len(kv) < 2) {
+			return nil, util.FmtNewtError(
+				"%s:%d: expected name=value", filename, num)
+		}
+
+		p := configPair{name: name}
+		if len(kv) == 2 {
+			p.val = strings.TrimSpace(kv[1])
+		}
+		pairs = append(pairs, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, util.ChildNewtError(err)
+	}
+
+	return pairs, nil
+}
+
+// Writes each setting in turn.  A failure is reported and the remaining
+// settings are still written.
+func configBatchWrite(s sesn.Sesn, pairs []configPair) error {
+	failed := 0
+	for _, p := range pairs {
+		c := xact.NewConfigWriteCmd()
+		c.SetTxOptions(nmutil.TxOptions())
+		c.Name = p.name
+		c.Val = p.val
+
+		res, err := c.Run(s)
+		if err != nil {
+			fmt.Printf("FAIL %s: %s\n", p.name, err.Error())
+			failed++
+		} else if rc := res.(*xact.ConfigWriteResult).Rsp.Rc; rc != 0 {
+			fmt.Printf("FAIL %s: error %d\n", p.name, rc)
+			failed++
+		} else {
+			fmt.Printf("OK   %s=%s\n", p.name, p.val)
+		}
+	}
+
+	fmt.Printf("%d of %d settings written\n", len(pairs)-failed, len(pairs))
+	if failed > 0 {
+		return util.FmtNewtError("%d settings failed", failed)
+	}
+
+	return nil
+}
+
+// Reads each named setting and prints it in the "name=value" format accepted
+// by --set-file.  Settings that can't be read are reported as comments.
+func configBatchRead(s sesn.Sesn, pairs []configPair) error {
+	failed := 0
+	for _, p := range pairs {
+		c := xact.NewConfigReadCmd()
+		c.SetTxOptions(nmutil.TxOptions())
+		c.Name = p.name
+
+		res, err := c.Run(s)
+		if err != nil {
+			fmt.Printf("# %s: %s\n", p.name, err.Error())
+			failed++
+		} else if rsp := res.(*xact.ConfigReadResult).Rsp; rsp.Rc != 0 {
+			fmt.Printf("# %s: error %d\n", p.name, rsp.Rc)
+			failed++
+		} else {
+			fmt.Printf("%s=%s\n", p.name, rsp.Val)
+		}
+	}
+
+	if failed > 0 {
+		return util.FmtNewtError("%d settings could not be read", failed)
+	}
+
+	return nil
+}
+
+func configBatchRunCmd(s sesn.Sesn) {
+	if configSetFile != "" {
+		pairs, err := readConfigFile(configSetFile, true)
+		if err != nil {
+			nmUsage(nil, err)
+		}
+
+		berr := configBatchWrite(s, pairs)
+		if configBatchSave {
+			configSave(s, nil)
+		}
+		if berr != nil {
+			nmUsage(nil, berr)
+		}
+	} else {
+		pairs, err := readConfigFile(configGetFile, false)
+		if err != nil {
+			nmUsage(nil, err)
+		}
+
+		if err := configBatchRead(s, pairs); err != nil {
+			nmUsage(nil, err)
+		}
+	}
+}
+
 func configRunCmd(cmd *cobra.Command, args []string) {
+	if configSetFile != "" && configGetFile != "" {
+		nmUsage(cmd, util.NewNewtError(
+			"--set-file and --get-file are mutually exclusive"))
+	}
+	batch := configSetFile != "" || configGetFile != ""
+	if batch && len(args) > 0 {
+		nmUsage(cmd, nil)
+	}
+
 	s, err := GetSesn()
 	if err != nil {
 		nmUsage(nil, err)
 	}
 
+	if batch {
+		configBatchRunCmd(s)
+		return
+	}
+
 	if len(args) == 1 {
 		if args[0] == "save" {
 			configSave(s, args)
@@ -107,10 +309,17 @@ func configRunCmd(cmd *cobra.Command, args []string) {
 func configCmd() *cobra.Command {
 	configCmdLongHelp := "Read or write a config value for <var-name> variable on " +
 		"a device.\nSpecify a var-value to write a value to a device.\n" +
-		"To persist existing configuration use 'save' as the var-name.\n"
+		"To persist existing configuration use 'save' as the var-name.\n\n" +
+		"Use --set-file to write many settings over one connection and\n" +
+		"--get-file to read them back.  Every setting is attempted; the\n" +
+		"command fails if any of them did.\n"
 	configEx := "    " + nmutil.ToolInfo.ExeName + " -c olimex config test/8\n"
 	configEx += "    " + nmutil.ToolInfo.ExeName + " -c olimex config test/8 1\n"
 	configEx += "    " + nmutil.ToolInfo.ExeName + " -c olimex config save\n"
+	configEx += "    " + nmutil.ToolInfo.ExeName +
+		" -c olimex config --set-file provision.txt --save\n"
+	configEx += "    " + nmutil.ToolInfo.ExeName +
+		" -c olimex config --get-file provision.txt > current.txt\n"
 	configCmd := &cobra.Command{
 		Use:     "config <var-name> [var-value] -c <conn_profile>",
 		Short:   "Read or write a config value on a device",
@@ -118,6 +327,12 @@ func configCmd() *cobra.Command {
 		Example: configEx,
 		Run:     configRunCmd,
 	}
+	configCmd.Flags().StringVar(&configSetFile, "set-file", "",
+		"Write every name=value setting in a file (or JSON object)")
+	configCmd.Flags().StringVar(&configGetFile, "get-file", "",
+		"Read every setting named in a file and print them as name=value")
+	configCmd.Flags().BoolVar(&configBatchSave, "save", false,
+		"Persist the configuration after --set-file")
 
 	return configCmd
 }
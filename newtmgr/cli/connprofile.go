@@ -21,6 +21,7 @@ package cli
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"mynewt.apache.org/newt/util"
@@ -42,6 +43,7 @@ func connProfileAddCmd(cmd *cobra.Command, args []string) {
 	cp := config.NewConnProfile()
 	cp.Name = name
 	cp.Type = config.CONN_TYPE_NONE
+	proto := ""
 
 	for _, vdef := range args[1:] {
 		s := strings.SplitN(vdef, "=", 2)
@@ -54,6 +56,29 @@ func connProfileAddCmd(cmd *cobra.Command, args []string) {
 			}
 		case "connstring":
 			cp.ConnString = s[1]
+		case "timeout":
+			t, err := strconv.ParseFloat(s[1], 64)
+			if err != nil || t <= 0 {
+				nmUsage(cmd, util.FmtNewtError("Invalid timeout: %s", s[1]))
+			}
+			cp.Timeout = t
+		case "tries":
+			t, err := strconv.Atoi(s[1])
+			if err != nil || t <= 0 {
+				nmUsage(cmd, util.FmtNewtError("Invalid tries: %s", s[1]))
+			}
+			cp.Tries = t
+		case "mtu":
+			m, err := strconv.Atoi(s[1])
+			if err != nil {
+				nmUsage(cmd, util.FmtNewtError("Invalid mtu: %s", s[1]))
+			}
+			if err := config.CheckMtu(m, 0); err != nil {
+				nmUsage(cmd, err)
+			}
+			cp.Mtu = m
+		case "proto":
+			proto = s[1]
 		default:
 			nmUsage(cmd, util.NewNewtError("Unknown variable "+s[0]))
 		}
@@ -65,6 +90,20 @@ func connProfileAddCmd(cmd *cobra.Command, args []string) {
 		nmUsage(cmd, util.NewNewtError("Must specify a connection type"))
 	}
 
+	if proto != "" {
+		var err error
+		cp.Type, err = config.ConnTypeWithProto(cp.Type, proto)
+		if err != nil {
+			nmUsage(cmd, err)
+		}
+	}
+
+	if cp.Mtu > 0 && !config.ConnTypeHasMtu(cp.Type) {
+		nmUsage(cmd, util.FmtNewtError(
+			"mtu is not supported for connection type %s",
+			config.ConnTypeToString(cp.Type)))
+	}
+
 	if err := cpm.AddConnProfile(cp); err != nil {
 		nmUsage(cmd, err)
 	}
@@ -97,8 +136,18 @@ func connProfileShowCmd(cmd *cobra.Command, args []string) {
 			found = true
 			fmt.Printf("Connection profiles: \n")
 		}
-		fmt.Printf("  %s: type=%s, connstring='%s'\n",
+		fmt.Printf("  %s: type=%s, connstring='%s'",
 			cp.Name, config.ConnTypeToString(cp.Type), cp.ConnString)
+		if cp.Timeout > 0 {
+			fmt.Printf(", timeout=%g", cp.Timeout)
+		}
+		if cp.Tries > 0 {
+			fmt.Printf(", tries=%d", cp.Tries)
+		}
+		if cp.Mtu > 0 {
+			fmt.Printf(", mtu=%d", cp.Mtu)
+		}
+		fmt.Printf("\n")
 	}
 
 	if !found {
@@ -110,6 +159,19 @@ func connProfileShowCmd(cmd *cobra.Command, args []string) {
 	}
 }
 
+func connProfileListCmd(cmd *cobra.Command, args []string) {
+	cpm := config.GlobalConnProfileMgr()
+
+	cpList, err := cpm.GetConnProfileList()
+	if err != nil {
+		nmUsage(cmd, err)
+	}
+
+	for _, cp := range cpList {
+		fmt.Printf("%-20s %s\n", cp.Name, config.ConnTypeToString(cp.Type))
+	}
+}
+
 func connProfileDelCmd(cmd *cobra.Command, args []string) {
 	cpm := config.GlobalConnProfileMgr()
 
@@ -135,10 +197,33 @@ func connProfileCmd() *cobra.Command {
 		},
 	}
 
+	addHelpText := "Add a connection profile, replacing any existing " +
+		"profile with the same name.\n\n" +
+		"Variables:\n" +
+		"  type=<conn_type>      connection type (required)\n" +
+		"  connstring=<string>   transport-specific connection string\n" +
+		"  timeout=<seconds>     default for --timeout\n" +
+		"  tries=<n>             default for --tries\n" +
+		"  mtu=<bytes>           default MTU; only used by the serial,\n" +
+		"                        oic_serial, udp, oic_udp and tcp types\n" +
+		"  proto=<smp|oic>       management framing; selects the plain or " +
+		"oic_\n" +
+		"                        variant of the connection type\n\n" +
+		"The plain connection types (serial, ble, bhd, udp, tcp) use the " +
+		"SMP header\nspoken by mcumgr devices; the oic_ types use CoAP.\n"
+
+	addEx := "  " + nmutil.ToolInfo.ExeName +
+		" conn add lab1 type=tcp connstring=tcp://10.0.0.5:4000 " +
+		"timeout=5 tries=3\n"
+	addEx += "  " + nmutil.ToolInfo.ExeName +
+		" conn add dev1 type=serial connstring=/dev/ttyUSB0 proto=oic\n"
+
 	addCmd := &cobra.Command{
-		Use:   "add <conn_profile> <varname=value ...> ",
-		Short: "Add a " + nmutil.ToolInfo.ShortName + " connection profile",
-		Run:   connProfileAddCmd,
+		Use:     "add <conn_profile> <varname=value ...> ",
+		Short:   "Add a " + nmutil.ToolInfo.ShortName + " connection profile",
+		Long:    addHelpText,
+		Example: addEx,
+		Run:     connProfileAddCmd,
 	}
 	cpCmd.AddCommand(addCmd)
 
@@ -161,5 +246,33 @@ func connProfileCmd() *cobra.Command {
 	}
 	cpCmd.AddCommand(showCmd)
 
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List " + nmutil.ToolInfo.ShortName + " connection profile names",
+		Run:   connProfileListCmd,
+	}
+	cpCmd.AddCommand(listCmd)
+
+	scanHelpText := "Scan for BLE peripherals advertising a management " +
+		"service and print their\naddress, RSSI, and name.  Requires a " +
+		"bhd or oic_bhd connection.  A device\ncan then be reached with " +
+		"--name.\n"
+
+	scanEx := "  " + nmutil.ToolInfo.ExeName +
+		" conn scan -c mybhd --duration 5\n"
+
+	scanCmd := &cobra.Command{
+		Use:     "scan -c <conn_profile>",
+		Short:   "Scan for BLE devices exposing the management service",
+		Long:    scanHelpText,
+		Example: scanEx,
+		Run:     connScanRunCmd,
+	}
+	scanCmd.Flags().Float64Var(&connScanDuration, "duration", 10,
+		"Scan duration, in seconds")
+	scanCmd.Flags().BoolVar(&connScanAll, "all", false,
+		"Report every advertiser, not just management devices")
+	cpCmd.AddCommand(scanCmd)
+
 	return cpCmd
 }
@@ -21,6 +21,8 @@ package cli
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -31,6 +33,42 @@ import (
 	"mynewt.apache.org/newtmgr/nmxact/xact"
 )
 
+var dateTimeSetNow bool
+var dateTimeTz string
+
+// Parses a timezone specifier: "local", "utc", "Z", or a UTC offset of the
+// form +HH:MM or -HH:MM.
+func parseTz(tz string) (*time.Location, error) {
+	switch strings.ToLower(tz) {
+	case "", "local":
+		return time.Local, nil
+	case "utc", "z":
+		return time.UTC, nil
+	}
+
+	bad := util.FmtNewtError("Invalid timezone offset: %s "+
+		"(expected local, utc, or +HH:MM)", tz)
+
+	if len(tz) != 6 || (tz[0] != '+' && tz[0] != '-') || tz[3] != ':' {
+		return nil, bad
+	}
+	hours, err := strconv.Atoi(tz[1:3])
+	if err != nil || hours > 14 {
+		return nil, bad
+	}
+	mins, err := strconv.Atoi(tz[4:6])
+	if err != nil || mins > 59 {
+		return nil, bad
+	}
+
+	secs := (hours*60 + mins) * 60
+	if tz[0] == '-' {
+		secs = -secs
+	}
+
+	return time.FixedZone(tz, secs), nil
+}
+
 func dateTimeRead(s sesn.Sesn) error {
 	c := xact.NewDateTimeReadCmd()
 	c.SetTxOptions(nmutil.TxOptions())
@@ -41,6 +79,11 @@ func dateTimeRead(s sesn.Sesn) error {
 	}
 
 	sres := res.(*xact.DateTimeReadResult)
+	if nmutil.JsonOutput {
+		printJson(sres.Rsp)
+		return nil
+	}
+
 	fmt.Println("Datetime(RFC 3339 format):", sres.Rsp.DateTime)
 
 	return nil
@@ -50,10 +93,15 @@ func dateTimeWrite(s sesn.Sesn, args []string) error {
 	c := xact.NewDateTimeWriteCmd()
 	c.SetTxOptions(nmutil.TxOptions())
 
-	if args[0] != "now" {
+	if !dateTimeSetNow && args[0] != "now" {
 		c.DateTime = args[0]
 	} else {
-		c.DateTime = time.Now().Format(time.RFC3339)
+		loc, err := parseTz(dateTimeTz)
+		if err != nil {
+			return err
+		}
+
+		c.DateTime = time.Now().In(loc).Format(time.RFC3339)
 		fmt.Printf("Setting time to %s\n", c.DateTime)
 	}
 
@@ -73,12 +121,20 @@ func dateTimeWrite(s sesn.Sesn, args []string) error {
 }
 
 func dateTimeRunCmd(cmd *cobra.Command, args []string) {
+	if dateTimeSetNow && len(args) > 0 {
+		nmUsage(cmd, util.NewNewtError(
+			"--set-now cannot be combined with a datetime value"))
+	}
+	if _, err := parseTz(dateTimeTz); err != nil {
+		nmUsage(cmd, err)
+	}
+
 	s, err := GetSesn()
 	if err != nil {
 		nmUsage(nil, err)
 	}
 
-	if len(args) == 0 {
+	if len(args) == 0 && !dateTimeSetNow {
 		if err := dateTimeRead(s); err != nil {
 			nmUsage(nil, err)
 		}
@@ -94,7 +150,9 @@ func dateTimeCmd() *cobra.Command {
 	dateTimeHelpText += "Specify a datetime-value\n"
 	dateTimeHelpText += "to set the datetime on the device.\n\n"
 	dateTimeHelpText += "Must specify datetime-value in RFC 3339 format, "
-	dateTimeHelpText += "or use keyword 'now'.\n"
+	dateTimeHelpText += "or use keyword 'now' or --set-now.  The current time "
+	dateTimeHelpText += "is sent in the\nhost's local timezone unless --tz "
+	dateTimeHelpText += "specifies otherwise.\n"
 
 	dateTimeEx := nmutil.ToolInfo.ExeName + " datetime -c myserial\n"
 	dateTimeEx += nmutil.ToolInfo.ExeName +
@@ -115,6 +173,9 @@ func dateTimeCmd() *cobra.Command {
 	dateTimeEx += nmutil.ToolInfo.ExeName +
 		" datetime now -c myserial " +
 		"                            (current system time)\n"
+	dateTimeEx += nmutil.ToolInfo.ExeName +
+		" datetime --set-now --tz utc -c myserial" +
+		"              (current system time, UTC)\n"
 
 	dateTimeCmd := &cobra.Command{
 		Use:     "datetime [rfc-3339-date-string] -c <conn_profile>",
@@ -123,6 +184,10 @@ func dateTimeCmd() *cobra.Command {
 		Example: dateTimeEx,
 		Run:     dateTimeRunCmd,
 	}
+	dateTimeCmd.Flags().BoolVar(&dateTimeSetNow, "set-now", false,
+		"Set the device to the host's current time")
+	dateTimeCmd.Flags().StringVar(&dateTimeTz, "tz", "local",
+		"Timezone for the current time: local, utc, or +HH:MM")
 
 	return dateTimeCmd
 }
@@ -23,10 +23,6 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
-
-	"mynewt.apache.org/newt/util"
-	"mynewt.apache.org/newtmgr/newtmgr/nmutil"
-	"mynewt.apache.org/newtmgr/nmxact/xact"
 )
 
 func echoRunCmd(cmd *cobra.Command, args []string) {
@@ -34,22 +30,18 @@ func echoRunCmd(cmd *cobra.Command, args []string) {
 		nmUsage(cmd, nil)
 	}
 
-	s, err := GetSesn()
+	c, err := GetClient()
 	if err != nil {
 		nmUsage(nil, err)
 	}
 
-	c := xact.NewEchoCmd()
-	c.SetTxOptions(nmutil.TxOptions())
-	c.Payload = args[0]
-
-	res, err := c.Run(s)
+	payload, err := c.Echo(args[0])
 	if err != nil {
-		nmUsage(nil, util.ChildNewtError(err))
+		clientErr(err)
+		return
 	}
 
-	eres := res.(*xact.EchoResult)
-	fmt.Println(eres.Rsp.Payload)
+	fmt.Println(payload)
 }
 
 func echoCmd() *cobra.Command {
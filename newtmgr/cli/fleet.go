@@ -0,0 +1,192 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"mynewt.apache.org/newt/util"
+	"mynewt.apache.org/newtmgr/newtmgr/config"
+	"mynewt.apache.org/newtmgr/newtmgr/nmutil"
+)
+
+var fleetConns string
+var fleetAllConns bool
+
+type fleetResult struct {
+	name string
+	err  error
+}
+
+func fleetMode() bool {
+	return fleetConns != "" || fleetAllConns
+}
+
+// Determines the connection profiles to run a fleet command against.
+func fleetProfiles() ([]string, error) {
+	if nmutil.ConnProfile != "" {
+		return nil, util.NewNewtError(
+			"--conn cannot be combined with --conns or --all-conns")
+	}
+
+	if fleetAllConns {
+		cpList, err := config.GlobalConnProfileMgr().GetConnProfileList()
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, len(cpList))
+		for i, cp := range cpList {
+			names[i] = cp.Name
+		}
+		if len(names) == 0 {
+			return nil, util.NewNewtError("No connection profiles found")
+		}
+		return names, nil
+	}
+
+	var names []string
+	for _, name := range strings.Split(fleetConns, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, err := config.GlobalConnProfileMgr().GetConnProfile(
+			name); err != nil {
+
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, util.NewNewtError("--conns lists no connection profiles")
+	}
+
+	return names, nil
+}
+
+// Strips the fleet options from the command line so that it can be rerun
+// against a single connection.
+func fleetChildArgs(args []string) []string {
+	var out []string
+
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--conns":
+			i++
+		case strings.HasPrefix(a, "--conns="):
+		case a == "--all-conns", strings.HasPrefix(a, "--all-conns="):
+		default:
+			out = append(out, a)
+		}
+	}
+
+	return out
+}
+
+// Copies a child's output to stdout one line at a time, prefixing each line
+// with the connection profile name.
+func fleetCopyOutput(name string, r io.Reader, mtx *sync.Mutex) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		mtx.Lock()
+		fmt.Printf("[%s] %s\n", name, scanner.Text())
+		mtx.Unlock()
+	}
+}
+
+func fleetRunOne(exe string, args []string, name string,
+	mtx *sync.Mutex) error {
+
+	// The profile goes ahead of the rest of the command line so that it
+	// is still parsed as a flag if the command line contains "--".
+	childArgs := append([]string{"-c", name}, args...)
+	cmd := exec.Command(exe, childArgs...)
+
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	done := make(chan struct{})
+	go func() {
+		fleetCopyOutput(name, pr, mtx)
+		close(done)
+	}()
+
+	err := cmd.Run()
+	pw.Close()
+	<-done
+
+	return err
+}
+
+// Reruns the current command line once per selected connection profile, with
+// all devices processed concurrently.  A failure on one device does not
+// affect the others.  Never returns.
+func fleetRun() {
+	names, err := fleetProfiles()
+	if err != nil {
+		nmUsage(nil, err)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		nmUsage(nil, util.ChildNewtError(err))
+	}
+	args := fleetChildArgs(os.Args[1:])
+
+	results := make([]fleetResult, len(names))
+	mtx := &sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i] = fleetResult{name, fleetRunOne(exe, args, name, mtx)}
+		}(i, name)
+	}
+	wg.Wait()
+
+	failed := 0
+	fmt.Printf("\nSummary:\n")
+	for _, r := range results {
+		if r.err == nil {
+			fmt.Printf("  %-20s PASS\n", r.name)
+		} else {
+			fmt.Printf("  %-20s FAIL (%s)\n", r.name, r.err.Error())
+			failed++
+		}
+	}
+	fmt.Printf("%d of %d devices succeeded\n", len(results)-failed,
+		len(results))
+
+	if failed > 0 {
+		NmExit(1)
+	}
+	NmExit(0)
+}
@@ -23,90 +23,175 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 
 	"mynewt.apache.org/newt/util"
 	"mynewt.apache.org/newtmgr/newtmgr/nmutil"
 	"mynewt.apache.org/newtmgr/nmxact/nmp"
+	"mynewt.apache.org/newtmgr/nmxact/sesn"
 	"mynewt.apache.org/newtmgr/nmxact/xact"
 )
 
-func fsDownloadRunCmd(cmd *cobra.Command, args []string) {
-	if len(args) < 2 {
-		nmUsage(cmd, nil)
-	}
+var fsRecursive bool
 
-	file, err := os.OpenFile(args[1], os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0660)
+func fsDownloadFile(s sesn.Sesn, src string, dst string) error {
+	file, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0660)
 	if err != nil {
-		nmUsage(cmd, util.FmtNewtError(
-			"Cannot open file %s - %s", args[1], err.Error()))
+		return util.FmtNewtError(
+			"Cannot open file %s - %s", dst, err.Error())
 	}
 	defer file.Close()
 
-	s, err := GetSesn()
-	if err != nil {
-		nmUsage(nil, err)
-	}
+	// The device only reports the file size in its first response.
+	var prog *xferProgress
+	var werr error
 
 	c := xact.NewFsDownloadCmd()
 	c.SetTxOptions(nmutil.TxOptions())
-	c.Name = args[0]
+	c.Name = src
 	c.ProgressCb = func(c *xact.FsDownloadCmd, rsp *nmp.FsDownloadRsp) {
-		fmt.Printf("%d\n", rsp.Off)
-		if _, err := file.Write(rsp.Data); err != nil {
-			nmUsage(nil, util.ChildNewtError(err))
+		if prog == nil {
+			prog = newXferProgress(int(rsp.Len))
+		}
+		if werr == nil {
+			_, werr = file.Write(rsp.Data)
 		}
+		prog.Set(int(rsp.Off) + len(rsp.Data))
 	}
 
 	res, err := c.Run(s)
+	if prog != nil {
+		prog.Finish()
+	}
 	if err != nil {
-		nmUsage(nil, util.ChildNewtError(err))
+		return util.ChildNewtError(err)
+	}
+	if werr != nil {
+		return util.ChildNewtError(werr)
 	}
 
 	sres := res.(*xact.FsDownloadResult)
 	rsp := sres.Rsps[len(sres.Rsps)-1]
 	if rsp.Rc != 0 {
-		fmt.Printf("Error: %d\n", rsp.Rc)
-		return
+		return util.FmtNewtError("fs download of %s failed; rc=%d", src, rsp.Rc)
 	}
 
-	fmt.Printf("Done\n")
+	return nil
 }
 
-func fsUploadRunCmd(cmd *cobra.Command, args []string) {
-	if len(args) < 2 {
-		nmUsage(cmd, nil)
-	}
-
-	data, err := ioutil.ReadFile(args[0])
+func fsUploadFile(s sesn.Sesn, src string, dst string) error {
+	data, err := ioutil.ReadFile(src)
 	if err != nil {
-		nmUsage(cmd, util.ChildNewtError(err))
+		return util.ChildNewtError(err)
 	}
 
-	s, err := GetSesn()
-	if err != nil {
-		nmUsage(nil, err)
-	}
+	prog := newXferProgress(len(data))
 
 	c := xact.NewFsUploadCmd()
 	c.SetTxOptions(nmutil.TxOptions())
-	c.Name = args[1]
+	c.Name = dst
 	c.Data = data
 	c.ProgressCb = func(c *xact.FsUploadCmd, rsp *nmp.FsUploadRsp) {
-		fmt.Printf("%d\n", rsp.Off)
+		prog.Set(int(rsp.Off))
 	}
 
 	res, err := c.Run(s)
+	prog.Finish()
 	if err != nil {
-		nmUsage(nil, util.ChildNewtError(err))
+		return util.ChildNewtError(err)
 	}
 
 	sres := res.(*xact.FsUploadResult)
 	rsp := sres.Rsps[len(sres.Rsps)-1]
 	if rsp.Rc != 0 {
-		fmt.Printf("Error: %d\n", rsp.Rc)
-		return
+		return util.FmtNewtError("fs upload of %s failed; rc=%d", dst, rsp.Rc)
+	}
+
+	return nil
+}
+
+// Collects the regular files beneath a local directory and pairs each with
+// its destination path on the device.  Remote paths always use '/' separators.
+func fsUploadList(src string, dst string) ([][2]string, error) {
+	var files [][2]string
+
+	err := filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		rdst := path.Join(dst, filepath.ToSlash(rel))
+		files = append(files, [2]string{p, rdst})
+		return nil
+	})
+	if err != nil {
+		return nil, util.ChildNewtError(err)
+	}
+
+	if len(files) == 0 {
+		return nil, util.FmtNewtError("No files found in %s", src)
+	}
+
+	return files, nil
+}
+
+func fsDownloadRunCmd(cmd *cobra.Command, args []string) {
+	if len(args) < 2 {
+		nmUsage(cmd, nil)
+	}
+
+	s, err := GetSesn()
+	if err != nil {
+		nmUsage(nil, err)
+	}
+
+	if err := fsDownloadFile(s, args[0], args[1]); err != nil {
+		nmUsage(nil, err)
+	}
+
+	fmt.Printf("Done\n")
+}
+
+func fsUploadRunCmd(cmd *cobra.Command, args []string) {
+	if len(args) < 2 {
+		nmUsage(cmd, nil)
+	}
+
+	files := [][2]string{{args[0], args[1]}}
+	if fsRecursive {
+		var err error
+		files, err = fsUploadList(args[0], args[1])
+		if err != nil {
+			nmUsage(cmd, err)
+		}
+	}
+
+	s, err := GetSesn()
+	if err != nil {
+		nmUsage(nil, err)
+	}
+
+	for i, f := range files {
+		if len(files) > 1 {
+			fmt.Printf("[%d/%d] %s -> %s\n", i+1, len(files), f[0], f[1])
+		}
+		if err := fsUploadFile(s, f[0], f[1]); err != nil {
+			if len(files) > 1 {
+				err = util.FmtNewtError("[%d/%d] %s",
+					i+1, len(files), err.Error())
+			}
+			nmUsage(nil, err)
+		}
 	}
 
 	fmt.Printf("Done\n")
@@ -123,6 +208,8 @@ func fsCmd() *cobra.Command {
 
 	uploadEx := "  " + nmutil.ToolInfo.ExeName +
 		" -c olimex fs upload sample.lua /sample.lua\n"
+	uploadEx += "  " + nmutil.ToolInfo.ExeName +
+		" -c olimex fs upload --recursive assets /assets\n"
 
 	uploadCmd := &cobra.Command{
 		Use:     "upload <src-filename> <dst-filename> -c <conn_profile>",
@@ -130,6 +217,9 @@ func fsCmd() *cobra.Command {
 		Example: uploadEx,
 		Run:     fsUploadRunCmd,
 	}
+	uploadCmd.Flags().BoolVar(&fsRecursive, "recursive",
+		false, "Upload every file beneath the source directory; remote "+
+			"directories must already exist")
 	fsCmd.AddCommand(uploadCmd)
 
 	downloadEx := "  " + nmutil.ToolInfo.ExeName +
@@ -20,6 +20,8 @@
 package cli
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"io/ioutil"
@@ -27,25 +29,150 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
-	pb "gopkg.in/cheggaaa/pb.v1"
 
 	"mynewt.apache.org/newt/util"
 	"mynewt.apache.org/newtmgr/newtmgr/core"
 	"mynewt.apache.org/newtmgr/newtmgr/nmutil"
+	"mynewt.apache.org/newtmgr/nmxact/client"
 	"mynewt.apache.org/newtmgr/nmxact/nmp"
 	"mynewt.apache.org/newtmgr/nmxact/xact"
 )
 
 var (
-	coreElfify   bool
-	coreOffset   uint32
-	coreNumBytes uint32
+	coreElfify    bool
+	coreOffset    uint32
+	coreNumBytes  uint32
+	coreAddr2line string
 )
 
 var noerase bool
 var upgrade bool
 var imageNum int
+var imageSlot int
+var eraseForce bool
 var maxWinSz int
+var chunkSz int
+var resume bool
+var verify bool
+
+const (
+	IMAGE_MAGIC               = 0x96f3b83d
+	IMAGE_TLV_INFO_MAGIC      = 0x6907
+	IMAGE_TLV_PROT_INFO_MAGIC = 0x6908
+	IMAGE_TLV_SHA256          = 0x10
+)
+
+type imageHdr struct {
+	Magic        uint32
+	LoadAddr     uint32
+	HdrSz        uint16
+	ProtectTlvSz uint16
+	ImgSz        uint32
+	Flags        uint32
+	Vers         [8]byte
+	Pad          uint32
+}
+
+type imageTlvInfo struct {
+	Magic  uint16
+	TlvTot uint16
+}
+
+type imageTlv struct {
+	Type uint8
+	Pad  uint8
+	Len  uint16
+}
+
+// Extracts the SHA256 hash TLV from an mcuboot image.  This is the hash the
+// device reports for the image in its image state response.
+func imageHash(data []byte) ([]byte, error) {
+	var hdr imageHdr
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian,
+		&hdr); err != nil {
+
+		return nil, util.FmtNewtError("Error reading image header: %s",
+			err.Error())
+	}
+	if hdr.Magic != IMAGE_MAGIC {
+		return nil, util.FmtNewtError("Invalid image magic: 0x%08x",
+			hdr.Magic)
+	}
+
+	off := int(hdr.HdrSz) + int(hdr.ImgSz)
+	for off < len(data) {
+		var info imageTlvInfo
+		r := bytes.NewReader(data[off:])
+		if err := binary.Read(r, binary.LittleEndian, &info); err != nil {
+			return nil, util.FmtNewtError("Error reading image TLV info: %s",
+				err.Error())
+		}
+
+		// Reject an area that is shorter than its own header or extends
+		// past the end of the image; either would derail the walk.
+		if int(info.TlvTot) < binary.Size(info) ||
+			off+int(info.TlvTot) > len(data) {
+
+			return nil, util.FmtNewtError(
+				"Invalid image TLV area size: %d", info.TlvTot)
+		}
+
+		switch info.Magic {
+		case IMAGE_TLV_PROT_INFO_MAGIC:
+			// The hash is never protected; skip this area.
+			off += int(info.TlvTot)
+
+		case IMAGE_TLV_INFO_MAGIC:
+			end := off + int(info.TlvTot)
+			off += binary.Size(info)
+			for off < end {
+				var tlv imageTlv
+				r := bytes.NewReader(data[off:])
+				if err := binary.Read(r, binary.LittleEndian,
+					&tlv); err != nil {
+
+					return nil, util.FmtNewtError(
+						"Error reading image TLV: %s", err.Error())
+				}
+				off += binary.Size(tlv)
+
+				if off+int(tlv.Len) > end {
+					return nil, util.FmtNewtError("Truncated image TLV")
+				}
+				if tlv.Type == IMAGE_TLV_SHA256 {
+					return data[off : off+int(tlv.Len)], nil
+				}
+				off += int(tlv.Len)
+			}
+			return nil, util.NewNewtError("Image does not contain a hash")
+
+		default:
+			return nil, util.FmtNewtError(
+				"Invalid image TLV info magic: 0x%04x", info.Magic)
+		}
+	}
+
+	return nil, util.NewNewtError("Image does not contain a hash")
+}
+
+// Reads the image state from the device and confirms that it contains an
+// image with the specified hash.
+func imageVerify(c *client.Client, hash []byte) error {
+	imgs, err := c.ImageState()
+	if err != nil {
+		return util.ChildNewtError(err)
+	}
+
+	for _, img := range imgs {
+		if img.Image == imageNum && bytes.Equal(img.Hash, hash) {
+			return nil
+		}
+	}
+
+	return util.FmtNewtError(
+		"Verification failed: device does not report an image with hash %x",
+		hash)
+}
 
 func imageFlagsStr(image nmp.ImageStateEntry) string {
 	strs := []string{}
@@ -66,7 +193,70 @@ func imageFlagsStr(image nmp.ImageStateEntry) string {
 	return strings.Join(strs, " ")
 }
 
+// Finds the image in the specified image number and slot.  Returns nil if the
+// slot is empty.
+func imageFindEntry(c *client.Client, image int,
+	slot int) (*nmp.ImageStateEntry, error) {
+
+	imgs, err := c.ImageState()
+	if err != nil {
+		return nil, util.ChildNewtError(err)
+	}
+
+	for i, img := range imgs {
+		if img.Image == image && img.Slot == slot {
+			return &imgs[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Determines the hash of the image a state command applies to: either the
+// hash given on the command line or that of the image in the slot selected
+// with --image and --slot.
+func imageStateHash(c *client.Client, args []string) ([]byte, error) {
+
+	if len(args) >= 1 {
+		if imageSlot >= 0 {
+			return nil, util.NewNewtError(
+				"Specify either an image hash or --slot, not both")
+		}
+
+		hexBytes, err := hex.DecodeString(args[0])
+		if err != nil {
+			return nil, util.ChildNewtError(err)
+		}
+		return hexBytes, nil
+	}
+
+	if imageSlot < 0 {
+		return nil, nil
+	}
+
+	img, err := imageFindEntry(c, imageNum, imageSlot)
+	if err != nil {
+		return nil, err
+	}
+	if img == nil {
+		return nil, util.FmtNewtError("No image in image=%d slot=%d",
+			imageNum, imageSlot)
+	}
+	if len(img.Hash) == 0 {
+		return nil, util.FmtNewtError(
+			"Device does not report a hash for image=%d slot=%d",
+			imageNum, imageSlot)
+	}
+
+	return img.Hash, nil
+}
+
 func imageStatePrintRsp(rsp *nmp.ImageStateRsp) error {
+	if nmutil.JsonOutput {
+		printJson(rsp)
+		return nil
+	}
+
 	if rsp.Rc != 0 {
 		fmt.Printf("Error: %d\n", rsp.Rc)
 		return nil
@@ -90,83 +280,66 @@ func imageStatePrintRsp(rsp *nmp.ImageStateRsp) error {
 }
 
 func imageStateListCmd(cmd *cobra.Command, args []string) {
-	s, err := GetSesn()
+	c, err := GetClient()
 	if err != nil {
 		nmUsage(nil, err)
 	}
 
-	c := xact.NewImageStateReadCmd()
-	c.SetTxOptions(nmutil.TxOptions())
-
-	res, err := c.Run(s)
+	rsp, err := c.ImageStateRead()
 	if err != nil {
-		nmUsage(nil, util.ChildNewtError(err))
+		clientErr(err)
+		return
 	}
-	ires := res.(*xact.ImageStateReadResult)
 
-	if err := imageStatePrintRsp(ires.Rsp); err != nil {
+	if err := imageStatePrintRsp(rsp); err != nil {
 		nmUsage(nil, err)
 	}
 }
 
 func imageStateTestCmd(cmd *cobra.Command, args []string) {
-	if len(args) < 1 {
+	if len(args) < 1 && imageSlot < 0 {
 		nmUsage(cmd, nil)
 	}
 
-	hexBytes, err := hex.DecodeString(args[0])
+	c, err := GetClient()
 	if err != nil {
-		nmUsage(cmd, util.ChildNewtError(err))
+		nmUsage(nil, err)
 	}
 
-	s, err := GetSesn()
+	hash, err := imageStateHash(c, args)
 	if err != nil {
-		nmUsage(nil, err)
+		nmUsage(cmd, err)
 	}
 
-	c := xact.NewImageStateWriteCmd()
-	c.SetTxOptions(nmutil.TxOptions())
-	c.Hash = hexBytes
-	c.Confirm = false
-
-	res, err := c.Run(s)
+	rsp, err := c.ImageStateWrite(hash, false)
 	if err != nil {
-		nmUsage(nil, util.ChildNewtError(err))
+		clientErr(err)
+		return
 	}
-	ires := res.(*xact.ImageStateWriteResult)
 
-	if err := imageStatePrintRsp(ires.Rsp); err != nil {
+	if err := imageStatePrintRsp(rsp); err != nil {
 		nmUsage(nil, err)
 	}
 }
 
 func imageStateConfirmCmd(cmd *cobra.Command, args []string) {
-	var hexBytes []byte
-	if len(args) >= 1 {
-		var err error
-		hexBytes, err = hex.DecodeString(args[0])
-		if err != nil {
-			nmUsage(cmd, util.ChildNewtError(err))
-		}
-	}
-
-	s, err := GetSesn()
+	c, err := GetClient()
 	if err != nil {
 		nmUsage(nil, err)
 	}
 
-	c := xact.NewImageStateWriteCmd()
-	c.SetTxOptions(nmutil.TxOptions())
-	c.Hash = hexBytes
-	c.Confirm = true
+	hash, err := imageStateHash(c, args)
+	if err != nil {
+		nmUsage(cmd, err)
+	}
 
-	res, err := c.Run(s)
+	rsp, err := c.ImageStateWrite(hash, true)
 	if err != nil {
-		nmUsage(nil, util.ChildNewtError(err))
+		clientErr(err)
+		return
 	}
-	ires := res.(*xact.ImageStateWriteResult)
 
-	if err := imageStatePrintRsp(ires.Rsp); err != nil {
+	if err := imageStatePrintRsp(rsp); err != nil {
 		nmUsage(nil, err)
 	}
 }
@@ -181,45 +354,57 @@ func imageUploadCmd(cmd *cobra.Command, args []string) {
 		nmUsage(cmd, util.NewNewtError(err.Error()))
 	}
 
-	s, err := GetSesn()
-	if err != nil {
-		nmUsage(nil, err)
+	var hash []byte
+	if verify {
+		hash, err = imageHash(imageFile)
+		if err != nil {
+			nmUsage(nil, err)
+		}
 	}
 
-	c := xact.NewImageUpgradeCmd()
-	c.SetTxOptions(nmutil.TxOptions())
-	c.Data = imageFile
-	if noerase == true {
-		c.NoErase = true
-	}
 	if imageNum < 0 {
 		nmUsage(cmd, util.NewNewtError("Invalid image number"))
 	}
-	c.ImageNum = imageNum
-	c.Upgrade = upgrade
-	c.ProgressBar = pb.StartNew(len(imageFile))
-	c.ProgressBar.SetUnits(pb.U_BYTES)
-	c.ProgressBar.ShowSpeed = true
-	c.LastOff = 0
-	c.MaxWinSz = maxWinSz
-	c.ProgressCb = func(cmd *xact.ImageUploadCmd, rsp *nmp.ImageUploadRsp) {
-		if rsp.Off > c.LastOff {
-			c.ProgressBar.Add(int(rsp.Off - c.LastOff))
-			c.LastOff = rsp.Off
-		}
+	if chunkSz <= 0 {
+		nmUsage(cmd, util.NewNewtError("Invalid chunk size"))
 	}
 
-	res, err := c.Run(s)
+	c, err := GetClient()
 	if err != nil {
-		nmUsage(nil, util.ChildNewtError(err))
+		nmUsage(nil, err)
+	}
+
+	if err := imageCompatCheck(c, args[0]); err != nil {
+		nmUsage(nil, err)
 	}
 
-	if res.Status() != 0 {
-		fmt.Printf("Error: %d\n", res.Status())
+	opts := client.ImageUpgradeOptions{
+		ImageNum: imageNum,
+		Upgrade:  upgrade,
+		NoErase:  noerase,
+		MaxWinSz: maxWinSz,
+		ChunkSz:  chunkSz,
+		Resume:   resume,
+	}
+
+	prog := newXferProgress(len(imageFile))
+	err = c.ImageUpgrade(imageFile, opts, func(off int, total int) {
+		prog.Set(off)
+	})
+	if err != nil {
+		clientErr(err)
 		return
 	}
 
-	c.ProgressBar.Finish()
+	prog.Finish()
+
+	if verify {
+		if err := imageVerify(c, hash); err != nil {
+			nmUsage(nil, err)
+		}
+		fmt.Printf("Verified image hash %x\n", hash)
+	}
+
 	fmt.Printf("Done\n")
 }
 
@@ -287,11 +472,18 @@ func coreDownloadCmd(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	var coreConvert *core.CoreConvert
 	if !coreElfify {
 		os.Rename(tmpName, args[0])
 		fmt.Printf("Done writing core file to %s\n", args[0])
+
+		coreConvert, err = core.ReadFilename(args[0])
+		if err != nil {
+			fmt.Printf("Cannot parse core file: %s\n", err.Error())
+			return
+		}
 	} else {
-		coreConvert, err := core.ConvertFilenames(tmpName, args[0])
+		coreConvert, err = core.ConvertFilenames(tmpName, args[0])
 		if err != nil {
 			nmUsage(nil, err)
 			return
@@ -300,6 +492,30 @@ func coreDownloadCmd(cmd *cobra.Command, args []string) {
 		fmt.Printf("Done writing core file to %s; hash=%x\n", args[0],
 			coreConvert.ImageHash)
 	}
+
+	if coreConvert != nil {
+		corePrintRegs(coreConvert)
+	}
+}
+
+// Prints the registers captured in a corefile for a quick triage read.
+func corePrintRegs(cc *core.CoreConvert) {
+	if len(cc.Regs) == 0 {
+		fmt.Printf("No registers in core file\n")
+		return
+	}
+
+	if pc, ok := cc.Pc(); ok {
+		fmt.Printf("Faulting PC: 0x%08x\n", pc)
+	}
+
+	fmt.Printf("Registers:\n")
+	for i, reg := range cc.Regs {
+		fmt.Printf("  %5s: 0x%08x", core.CoreRegName(i), reg)
+		if i%4 == 3 || i == len(cc.Regs)-1 {
+			fmt.Printf("\n")
+		}
+	}
 }
 
 func coreEraseCmd(cmd *cobra.Command, args []string) {
@@ -325,27 +541,68 @@ func coreEraseCmd(cmd *cobra.Command, args []string) {
 	fmt.Printf("Done\n")
 }
 
+// Checks that erasing a slot won't destroy the image that is running or that
+// the device will boot.
+func imageEraseCheck(c *client.Client, image int, slot int) error {
+	img, err := imageFindEntry(c, image, slot)
+	if err != nil {
+		return err
+	}
+
+	if img == nil {
+		fmt.Printf("Erasing image=%d slot=%d: no image\n", image, slot)
+		return nil
+	}
+
+	fmt.Printf("Erasing image=%d slot=%d: version=%s flags=%s\n",
+		image, slot, img.Version, imageFlagsStr(*img))
+
+	if img.Active || img.Confirmed {
+		return util.FmtNewtError(
+			"Refusing to erase image=%d slot=%d: it holds the %s image; "+
+				"use --force to erase it anyway",
+			image, slot, imageFlagsStr(*img))
+	}
+
+	return nil
+}
+
 func imageEraseCmd(cmd *cobra.Command, args []string) {
-	s, err := GetSesn()
+	if imageNum < 0 || imageSlot > 1 {
+		nmUsage(cmd, util.NewNewtError(
+			"--image must be >= 0 and --slot must be 0 or 1"))
+	}
+
+	c, err := GetClient()
 	if err != nil {
 		nmUsage(nil, err)
 	}
 
-	c := xact.NewImageEraseCmd()
-	c.SetTxOptions(nmutil.TxOptions())
+	// The device erases the secondary slot of the first image by default.
+	// Otherwise, the request carries a slot index that counts across
+	// images: image n's slots are 2n and 2n+1.
+	slot := imageSlot
+	if slot < 0 {
+		slot = 1
+	}
+	flat := -1
+	if imageSlot >= 0 || imageNum != 0 {
+		flat = imageNum*2 + slot
+	}
 
-	res, err := c.Run(s)
-	if err != nil {
-		nmUsage(nil, util.ChildNewtError(err))
+	if eraseForce {
+		fmt.Printf("Erasing image=%d slot=%d without checking its state\n",
+			imageNum, slot)
+	} else if err := imageEraseCheck(c, imageNum, slot); err != nil {
+		nmUsage(nil, err)
 	}
-	ires := res.(*xact.ImageEraseResult)
 
-	if ires.Status() != 0 {
-		fmt.Printf("Error: %d\n", ires.Status())
+	if err := c.ImageErase(flat); err != nil {
+		clientErr(err)
 		return
 	}
 
-	fmt.Printf("Done\n")
+	fmt.Printf("Done; erased image=%d slot=%d\n", imageNum, slot)
 }
 
 func coreConvertCmd(cmd *cobra.Command, args []string) {
@@ -361,6 +618,27 @@ func coreConvertCmd(cmd *cobra.Command, args []string) {
 	}
 
 	fmt.Printf("Corefile created for\n   %x\n", coreConvert.ImageHash)
+	corePrintRegs(coreConvert)
+}
+
+func coreBacktraceCmd(cmd *cobra.Command, args []string) {
+	if len(args) < 2 {
+		nmUsage(cmd, nil)
+	}
+
+	coreConvert, err := core.ReadFilename(args[0])
+	if err != nil {
+		nmUsage(nil, err)
+	}
+
+	frames, err := coreConvert.Backtrace(args[1], coreAddr2line)
+	if err != nil {
+		nmUsage(nil, err)
+	}
+
+	for i, f := range frames {
+		fmt.Println(f.BtLine(i))
+	}
 }
 
 func imageCmd() *cobra.Command {
@@ -380,22 +658,32 @@ func imageCmd() *cobra.Command {
 	imageCmd.AddCommand(listCmd)
 
 	testCmd := &cobra.Command{
-		Use:   "test <hex-image-hash>",
+		Use:   "test <hex-image-hash | --slot n> -c <conn_profile>",
 		Short: "Test an image on next reboot",
-		Run:   imageStateTestCmd,
+		Long: "Mark an image to be run once on the next reboot.  The image " +
+			"is identified by its\nhash, or by --slot (and --image in a " +
+			"multi-image system).",
+		Run: imageStateTestCmd,
 	}
 	imageCmd.AddCommand(testCmd)
 
 	confirmCmd := &cobra.Command{
-		Use:   "confirm [hex-image-hash] -c <conn_profile>",
+		Use:   "confirm [hex-image-hash | --slot n] -c <conn_profile>",
 		Short: "Permanently run image",
-		Long: "If a hash is specified, permanently switch to the " +
-			"corresponding image.  If no hash is specified, the current " +
+		Long: "If a hash or --slot is specified, permanently switch to the " +
+			"corresponding image.  If neither is specified, the current " +
 			"image setup is made permanent.",
 		Run: imageStateConfirmCmd,
 	}
 	imageCmd.AddCommand(confirmCmd)
 
+	for _, c := range []*cobra.Command{testCmd, confirmCmd} {
+		c.Flags().IntVar(&imageSlot, "slot", -1,
+			"Select the image in this slot instead of specifying a hash")
+		c.Flags().IntVarP(&imageNum, "image", "n", 0,
+			"In a multi-image system, the image that --slot refers to")
+	}
+
 	uploadEx := "  " + nmutil.ToolInfo.ExeName +
 		" -c olimex image upload bin/slinky_zero/apps/slinky.img\n"
 
@@ -419,6 +707,25 @@ func imageCmd() *cobra.Command {
 		"maxwinsize", "w", xact.IMAGE_UPLOAD_DEF_MAX_WS,
 		"Set the maximum size for the window of outstanding chunks in transit. "+
 			"caution:higher num may not translate to better perf and may result in errors")
+	uploadCmd.PersistentFlags().IntVar(&chunkSz,
+		"chunksize", xact.IMAGE_UPLOAD_MAX_CHUNK,
+		"Maximum number of image bytes to send per request; chunks are "+
+			"shortened as needed to fit the transport's MTU")
+	uploadCmd.PersistentFlags().BoolVar(&verify,
+		"verify", false,
+		"After uploading, read the image state back from the device and "+
+			"check that it reports the image's hash")
+	uploadCmd.PersistentFlags().BoolVar(&resume,
+		"resume", false,
+		"Continue a previously interrupted upload of the same image from the "+
+			"offset reported by the device")
+	uploadCmd.PersistentFlags().StringVar(&uploadManifest,
+		"manifest", "",
+		"Build manifest to check the image's BSP against the device "+
+			"(default: manifest.json beside the image)")
+	uploadCmd.PersistentFlags().BoolVar(&uploadForce,
+		"force", false,
+		"Upload even if the image was built for a different BSP")
 	imageCmd.AddCommand(uploadCmd)
 
 	coreListCmd := &cobra.Command{
@@ -436,6 +743,7 @@ func imageCmd() *cobra.Command {
 
 	coreDownloadCmd := &cobra.Command{
 		Use:     "coredownload <core-file> -c <conn_profile>",
+		Aliases: []string{"coredump"},
 		Short:   "Download core from a device",
 		Example: coreEx,
 		Run:     coreDownloadCmd,
@@ -462,8 +770,15 @@ func imageCmd() *cobra.Command {
 	imageEraseHelpText += "The image cannot be erased if the image is a confirmed image, is marked\n"
 	imageEraseHelpText += "for test on the next reboot, or is an active image for a split image setup.\n"
 
+	imageEraseHelpText += "Use --image and --slot to erase a different slot, if the device\n"
+	imageEraseHelpText += "supports it.\n\n"
+	imageEraseHelpText += "The slot's image state is checked first; an active or confirmed image\n"
+	imageEraseHelpText += "is only erased with --force.\n"
+
 	imageEraseEx := "  " + nmutil.ToolInfo.ExeName +
 		" -c olimex image erase\n"
+	imageEraseEx += "  " + nmutil.ToolInfo.ExeName +
+		" -c olimex image erase --image 1 --slot 1\n"
 
 	imageEraseCmd := &cobra.Command{
 		Use:     "erase -c <conn_profile>",
@@ -472,6 +787,12 @@ func imageCmd() *cobra.Command {
 		Example: imageEraseEx,
 		Run:     imageEraseCmd,
 	}
+	imageEraseCmd.Flags().IntVar(&imageSlot, "slot", -1,
+		"Slot within the image to erase (default: the secondary slot)")
+	imageEraseCmd.Flags().IntVarP(&imageNum, "image", "n", 0,
+		"In a multi-image system, the image whose slot is erased")
+	imageEraseCmd.Flags().BoolVar(&eraseForce, "force", false,
+		"Erase even if the slot holds the active or confirmed image")
 	imageCmd.AddCommand(imageEraseCmd)
 
 	coreConvertCmd := &cobra.Command{
@@ -481,5 +802,25 @@ func imageCmd() *cobra.Command {
 	}
 	imageCmd.AddCommand(coreConvertCmd)
 
+	coreBtHelpText := "Print a backtrace of the crashed thread in a core " +
+		"downloaded without\n--elfify.  Frames after the link register are " +
+		"found by scanning the\nstack for return addresses, so some may be " +
+		"spurious.\n"
+
+	coreBtEx := "  " + nmutil.ToolInfo.ExeName +
+		" image corebt core bin/targets/blinky/app/apps/blinky/blinky.elf\n"
+
+	coreBtCmd := &cobra.Command{
+		Use:     "corebt <core-filename> <elf-filename>",
+		Short:   "Print a symbolicated backtrace from a core",
+		Long:    coreBtHelpText,
+		Example: coreBtEx,
+		Run:     coreBacktraceCmd,
+	}
+	coreBtCmd.Flags().StringVar(&coreAddr2line, "addr2line",
+		"arm-none-eabi-addr2line",
+		"addr2line executable used to find source lines; empty to disable")
+	imageCmd.AddCommand(coreBtCmd)
+
 	return imageCmd
 }
@@ -0,0 +1,145 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"mynewt.apache.org/newt/util"
+	"mynewt.apache.org/newtmgr/nmxact/client"
+)
+
+// The config setting through which the sys/id package reports the device's
+// BSP.
+const idBspConfigName = "id/bsp"
+
+var uploadManifest string
+var uploadForce bool
+
+// The subset of a newt build manifest needed to identify the target BSP.
+type imageManifest struct {
+	Target []string `json:"target"`
+}
+
+// Locates the manifest for an image: the one specified with --manifest, or
+// the manifest.json that newt writes alongside the image.  Returns "" if
+// there isn't one.
+func imageManifestPath(imgFilename string) string {
+	if uploadManifest != "" {
+		return uploadManifest
+	}
+
+	p := filepath.Join(filepath.Dir(imgFilename), "manifest.json")
+	if _, err := os.Stat(p); err != nil {
+		return ""
+	}
+
+	return p
+}
+
+// Reads the BSP package that an image was built for from its manifest.
+func imageManifestBsp(filename string) (string, error) {
+	blob, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", util.ChildNewtError(err)
+	}
+
+	m := imageManifest{}
+	if err := json.Unmarshal(blob, &m); err != nil {
+		return "", util.FmtNewtError("Invalid manifest %s: %s",
+			filename, err.Error())
+	}
+
+	for _, tv := range m.Target {
+		if strings.HasPrefix(tv, "target.bsp=") {
+			return strings.TrimPrefix(tv, "target.bsp="), nil
+		}
+	}
+
+	return "", util.FmtNewtError("Manifest %s does not specify a BSP",
+		filename)
+}
+
+// Reads the device's BSP name.  Returns "" if the device doesn't report it.
+func deviceBsp(c *client.Client) (string, error) {
+	val, err := c.ConfigRead(idBspConfigName)
+	if err != nil {
+		if client.IsRc(err) {
+			return "", nil
+		}
+		return "", util.ChildNewtError(err)
+	}
+
+	return val, nil
+}
+
+// Refuses an upload if the image was built for a different BSP than the
+// device runs.  The check is skipped if either side doesn't identify its BSP.
+// A device may report either the full BSP package name or just its base name.
+func imageCompatCheck(c *client.Client, imgFilename string) error {
+	// An image without a manifest is the common case; it is not worth a
+	// notice.  A manifest given with --manifest must exist.
+	mpath := imageManifestPath(imgFilename)
+	if mpath == "" {
+		log.Debugf("No manifest found; skipping compatibility check")
+		return nil
+	}
+
+	imgBsp, err := imageManifestBsp(mpath)
+	if err != nil {
+		return err
+	}
+
+	devBsp, err := deviceBsp(c)
+	if err != nil {
+		return err
+	}
+	if devBsp == "" {
+		if uploadManifest != "" {
+			fmt.Printf("Device does not report %s; skipping "+
+				"compatibility check\n", idBspConfigName)
+		} else {
+			log.Debugf("Device does not report %s; skipping "+
+				"compatibility check", idBspConfigName)
+		}
+		return nil
+	}
+
+	if devBsp == imgBsp || devBsp == path.Base(imgBsp) {
+		return nil
+	}
+
+	if uploadForce {
+		fmt.Printf("Warning: image built for %s; device reports %s\n",
+			imgBsp, devBsp)
+		return nil
+	}
+
+	return util.FmtNewtError("Image built for BSP %s, but device reports "+
+		"%s; use --force to upload anyway", imgBsp, devBsp)
+}
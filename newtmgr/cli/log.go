@@ -25,6 +25,8 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -37,6 +39,25 @@ import (
 )
 
 var optLogShowFull bool
+var optLogShowFollow bool
+var optLogShowSince uint32
+var optLogShowLevel string
+var optLogShowModule string
+var optLogShowTimeFormat string
+
+// Difference between the host clock and the device clock; used to render
+// absolute timestamps.
+var logTsOffset time.Duration
+
+// The device's datetime and the host time at which it was read; used to
+// render relative timestamps.
+var logDevNow time.Time
+var logDevNowRead time.Time
+
+// When following a log, the polling delay doubles after each poll that
+// retrieves no new entries, up to the maximum.
+const logFollowMinDelay = 250 * time.Millisecond
+const logFollowMaxDelay = 5 * time.Second
 
 // Converts the provided CBOR map to a JSON string.
 func logCborMsgText(cborMap []byte) (string, error) {
@@ -99,6 +120,187 @@ func logShowParseArgs(args []string) (*logShowCfg, error) {
 	return cfg, nil
 }
 
+// Restricts the entries printed by the log show commands.  A negative value
+// means the corresponding filter is disabled.
+type logFilter struct {
+	MinLevel int
+	Module   int
+}
+
+// Resolves a log level or module name to its numeric value.  Numbers are used
+// as is.  Names are looked up in the standard tables first, then in the map
+// the device reports, so that custom modules and levels can be used by name.
+func logResolveName(s string, std func(string) (int, error),
+	devMap func() (map[string]int, error)) (int, error) {
+
+	if u64, err := strconv.ParseUint(s, 0, 8); err == nil {
+		return int(u64), nil
+	}
+
+	if v, err := std(s); err == nil {
+		return v, nil
+	}
+
+	m, err := devMap()
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, s) {
+			return v, nil
+		}
+	}
+
+	return 0, util.FmtNewtError("Unknown name: %s", s)
+}
+
+func logDevModuleMap(s sesn.Sesn) (map[string]int, error) {
+	c := xact.NewLogModuleListCmd()
+	c.SetTxOptions(nmutil.TxOptions())
+
+	res, err := c.Run(s)
+	if err != nil {
+		return nil, util.ChildNewtError(err)
+	}
+
+	return res.(*xact.LogModuleListResult).Rsp.Map, nil
+}
+
+func logDevLevelMap(s sesn.Sesn) (map[string]int, error) {
+	c := xact.NewLogLevelListCmd()
+	c.SetTxOptions(nmutil.TxOptions())
+
+	res, err := c.Run(s)
+	if err != nil {
+		return nil, util.ChildNewtError(err)
+	}
+
+	return res.(*xact.LogLevelListResult).Rsp.Map, nil
+}
+
+func buildLogFilter(s sesn.Sesn) (*logFilter, error) {
+	f := &logFilter{
+		MinLevel: -1,
+		Module:   -1,
+	}
+
+	var err error
+	if optLogShowLevel != "" {
+		f.MinLevel, err = logResolveName(optLogShowLevel,
+			nmp.LogLevelFromString,
+			func() (map[string]int, error) { return logDevLevelMap(s) })
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if optLogShowModule != "" {
+		f.Module, err = logResolveName(optLogShowModule,
+			nmp.LogModuleFromString,
+			func() (map[string]int, error) { return logDevModuleMap(s) })
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// Removes the entries that don't pass the filter from the response.
+func (f *logFilter) apply(rsp *nmp.LogShowRsp) {
+	if f == nil || (f.MinLevel < 0 && f.Module < 0) {
+		return
+	}
+
+	for i := range rsp.Logs {
+		log := &rsp.Logs[i]
+
+		entries := log.Entries[:0]
+		for _, entry := range log.Entries {
+			if f.MinLevel >= 0 && int(entry.Level) < f.MinLevel {
+				continue
+			}
+			if f.Module >= 0 && int(entry.Module) != f.Module {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		log.Entries = entries
+	}
+}
+
+// Parses the datetime string reported by a device.  Devices that don't report
+// a time zone are assumed to be in UTC.
+func parseDevDateTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse("2006-01-02T15:04:05.999999999", s)
+	if err != nil {
+		return time.Time{}, util.FmtNewtError(
+			"Cannot parse device datetime \"%s\"", s)
+	}
+
+	return t, nil
+}
+
+// Reads the device's datetime and records it, along with its offset from the
+// host clock.
+// Until the device clock is set, log timestamps and the device datetime both
+// count from boot, so the offset maps either kind of timestamp to wall-clock
+// time.
+func logReadTsOffset(s sesn.Sesn) error {
+	c := xact.NewDateTimeReadCmd()
+	c.SetTxOptions(nmutil.TxOptions())
+
+	res, err := c.Run(s)
+	if err != nil {
+		return util.ChildNewtError(err)
+	}
+	now := time.Now()
+
+	sres := res.(*xact.DateTimeReadResult)
+	if sres.Rsp.Rc != 0 {
+		return util.FmtNewtError("Datetime read failed: %d", sres.Rsp.Rc)
+	}
+
+	devNow, err := parseDevDateTime(sres.Rsp.DateTime)
+	if err != nil {
+		return err
+	}
+
+	logDevNow = devNow
+	logDevNowRead = now
+	logTsOffset = now.Sub(devNow)
+	return nil
+}
+
+// Renders a log entry timestamp, in microseconds, according to the
+// --time-format setting.
+func logTimestampStr(ts int64) string {
+	d := time.Duration(ts) * time.Microsecond
+
+	switch optLogShowTimeFormat {
+	case "relative":
+		// Log timestamps and the device datetime share a time base, so
+		// the difference is meaningful whether or not the device clock has
+		// been set.  Only the time elapsed since the datetime was read is
+		// measured on the host, so entries retrieved later (--follow) are
+		// handled too.
+		devNow := logDevNow.Add(time.Since(logDevNowRead))
+		age := devNow.Sub(time.Unix(0, 0).Add(d))
+		return age.Round(time.Millisecond).String() + " ago"
+
+	case "absolute":
+		t := time.Unix(0, 0).Add(d).Add(logTsOffset)
+		return t.Local().Format("2006-01-02 15:04:05.000000")
+
+	default:
+		return fmt.Sprintf("%dus", ts)
+	}
+}
+
 func printLogShowRsp(rsp *nmp.LogShowRsp, printHdr bool) {
 	if len(rsp.Logs) == 0 {
 		fmt.Printf("(no logs retrieved)\n")
@@ -145,9 +347,9 @@ func printLogShowRsp(rsp *nmp.LogShowRsp, printHdr bool) {
 				msgText = hex.EncodeToString(entry.Msg)
 			}
 
-			fmt.Printf("%10d %20dus | %16s %16s %6s %8s %s\n",
+			fmt.Printf("%10d %22s | %16s %16s %6s %8s %s\n",
 				entry.Index,
-				entry.Timestamp,
+				logTimestampStr(entry.Timestamp),
 				modText,
 				levText,
 				entry.Type,
@@ -157,7 +359,7 @@ func printLogShowRsp(rsp *nmp.LogShowRsp, printHdr bool) {
 	}
 }
 
-func logShowFullCmd(s sesn.Sesn, cfg *logShowCfg) error {
+func logShowFullCmd(s sesn.Sesn, cfg *logShowCfg, f *logFilter) error {
 	if cfg.Name == "" {
 		return util.FmtNewtError("must specify a single log to read when `-a` is used")
 	}
@@ -169,8 +371,14 @@ func logShowFullCmd(s sesn.Sesn, cfg *logShowCfg) error {
 	c.Index = cfg.Index
 
 	first := true
+	var rsps []*nmp.LogShowRsp
 	c.ProgressCb = func(_ *xact.LogShowFullCmd, rsp *nmp.LogShowRsp) {
-		printLogShowRsp(rsp, first)
+		f.apply(rsp)
+		if nmutil.JsonOutput {
+			rsps = append(rsps, rsp)
+		} else {
+			printLogShowRsp(rsp, first)
+		}
 		first = false
 	}
 
@@ -179,10 +387,77 @@ func logShowFullCmd(s sesn.Sesn, cfg *logShowCfg) error {
 		return err
 	}
 
+	if nmutil.JsonOutput {
+		printJson(rsps)
+	}
+
 	return nil
 }
 
-func logShowPartialCmd(s sesn.Sesn, cfg *logShowCfg) error {
+func logShowFollowCmd(s sesn.Sesn, cfg *logShowCfg, f *logFilter) error {
+	if cfg.Name == "" {
+		return util.FmtNewtError("must specify a single log to read when `--follow` is used")
+	}
+
+	idx := cfg.Index
+	delay := logFollowMinDelay
+	first := true
+
+	for {
+		c := xact.NewLogShowFullCmd()
+		c.SetTxOptions(nmutil.TxOptions())
+		c.Name = cfg.Name
+		c.Index = idx
+
+		retrieved := false
+		c.ProgressCb = func(_ *xact.LogShowFullCmd, rsp *nmp.LogShowRsp) {
+			numEntries := 0
+			for _, log := range rsp.Logs {
+				if len(log.Entries) > 0 {
+					numEntries += len(log.Entries)
+					idx = log.Entries[len(log.Entries)-1].Index + 1
+				}
+			}
+			if numEntries == 0 {
+				return
+			}
+			retrieved = true
+
+			f.apply(rsp)
+			numEntries = 0
+			for _, log := range rsp.Logs {
+				numEntries += len(log.Entries)
+			}
+			if numEntries == 0 {
+				return
+			}
+
+			if nmutil.JsonOutput {
+				printJson(rsp)
+			} else {
+				printLogShowRsp(rsp, first)
+			}
+			first = false
+		}
+
+		if _, err := c.Run(s); err != nil {
+			return err
+		}
+
+		if retrieved {
+			delay = logFollowMinDelay
+		} else if delay < logFollowMaxDelay {
+			delay *= 2
+			if delay > logFollowMaxDelay {
+				delay = logFollowMaxDelay
+			}
+		}
+
+		time.Sleep(delay)
+	}
+}
+
+func logShowPartialCmd(s sesn.Sesn, cfg *logShowCfg, f *logFilter) error {
 	c := xact.NewLogShowCmd()
 	c.SetTxOptions(nmutil.TxOptions())
 
@@ -196,6 +471,12 @@ func logShowPartialCmd(s sesn.Sesn, cfg *logShowCfg) error {
 	}
 
 	sres := res.(*xact.LogShowResult)
+	f.apply(sres.Rsp)
+	if nmutil.JsonOutput {
+		printJson(sres.Rsp)
+		return nil
+	}
+
 	fmt.Printf("Status: %d\n", sres.Status())
 	fmt.Printf("Next index: %d\n", sres.Rsp.NextIndex)
 	if len(sres.Rsp.Logs) == 0 {
@@ -213,15 +494,39 @@ func logShowCmd(cmd *cobra.Command, args []string) {
 		nmUsage(cmd, err)
 	}
 
+	switch optLogShowTimeFormat {
+	case "raw", "relative", "absolute":
+	default:
+		nmUsage(cmd, util.FmtNewtError("Invalid time format: %s",
+			optLogShowTimeFormat))
+	}
+
+	if cmd.Flags().Changed("since") {
+		cfg.Index = optLogShowSince
+	}
+
 	s, err := GetSesn()
 	if err != nil {
 		nmUsage(nil, err)
 	}
 
-	if optLogShowFull {
-		err = logShowFullCmd(s, cfg)
+	f, err := buildLogFilter(s)
+	if err != nil {
+		nmUsage(cmd, err)
+	}
+
+	if optLogShowTimeFormat != "raw" {
+		if err := logReadTsOffset(s); err != nil {
+			nmUsage(nil, err)
+		}
+	}
+
+	if optLogShowFollow {
+		err = logShowFollowCmd(s, cfg, f)
+	} else if optLogShowFull {
+		err = logShowFullCmd(s, cfg, f)
 	} else {
-		err = logShowPartialCmd(s, cfg)
+		err = logShowPartialCmd(s, cfg, f)
 	}
 	if err != nil {
 		nmUsage(nil, err)
@@ -243,6 +548,11 @@ func logListCmd(cmd *cobra.Command, args []string) {
 	}
 
 	sres := res.(*xact.LogListResult)
+	if nmutil.JsonOutput {
+		printJson(sres.Rsp)
+		return
+	}
+
 	if sres.Rsp.Rc != 0 {
 		fmt.Printf("error: %d\n", sres.Rsp.Rc)
 		return
@@ -271,6 +581,11 @@ func logModuleListCmd(cmd *cobra.Command, args []string) {
 	}
 
 	sres := res.(*xact.LogModuleListResult)
+	if nmutil.JsonOutput {
+		printJson(sres.Rsp)
+		return
+	}
+
 	if sres.Rsp.Rc != 0 {
 		fmt.Printf("error: %d\n", sres.Rsp.Rc)
 		return
@@ -303,6 +618,11 @@ func logLevelListCmd(cmd *cobra.Command, args []string) {
 	}
 
 	sres := res.(*xact.LogLevelListResult)
+	if nmutil.JsonOutput {
+		printJson(sres.Rsp)
+		return
+	}
+
 	if sres.Rsp.Rc != 0 {
 		fmt.Printf("error: %d\n", sres.Rsp.Rc)
 		return
@@ -365,6 +685,7 @@ func logCmd() *cobra.Command {
 	logShowEx += nmutil.ToolInfo.ExeName + " log show reboot_log last -c myserial\n"
 	logShowEx += nmutil.ToolInfo.ExeName + " log show reboot_log 5 -c myserial\n"
 	logShowEx += nmutil.ToolInfo.ExeName + " log show reboot_log 3 1122222 -c myserial\n"
+	logShowEx += nmutil.ToolInfo.ExeName + " log show reboot_log --follow --since 100 -c myserial\n"
 
 	showCmd := &cobra.Command{
 		Use:     "show [log-name [min-index [min-timestamp]]] -c <conn_profile>",
@@ -374,6 +695,19 @@ func logCmd() *cobra.Command {
 		Run:     logShowCmd,
 	}
 	showCmd.PersistentFlags().BoolVarP(&optLogShowFull, "all", "a", false, "read until end of log")
+	showCmd.PersistentFlags().BoolVarP(&optLogShowFollow, "follow", "f", false,
+		"keep polling the log and print new entries as they arrive")
+	showCmd.PersistentFlags().Uint32Var(&optLogShowSince, "since", 0,
+		"start reading at this entry index; overrides min-index")
+	showCmd.PersistentFlags().StringVar(&optLogShowLevel, "level", "",
+		"only show entries at or above this level (name or number)")
+	showCmd.PersistentFlags().StringVar(&optLogShowModule, "module", "",
+		"only show entries from this module (name or number)")
+	showCmd.PersistentFlags().StringVar(&optLogShowTimeFormat, "time-format",
+		"raw", "how to display entry timestamps: raw (microseconds), "+
+			"relative (age according to the device's datetime), "+
+			"or absolute (wall-clock time derived from the "+
+			"device's datetime)")
 	logCmd.AddCommand(showCmd)
 
 	clearCmd := &cobra.Command{
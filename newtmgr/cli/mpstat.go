@@ -45,6 +45,11 @@ func mempoolStatRunCmd(cmd *cobra.Command, args []string) {
 	}
 
 	sres := res.(*xact.MempoolStatResult)
+	if nmutil.JsonOutput {
+		printJson(sres.Rsp)
+		return
+	}
+
 	if sres.Rsp.Rc != 0 {
 		fmt.Printf("Error: %d\n", sres.Rsp.Rc)
 		return
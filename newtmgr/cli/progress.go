@@ -0,0 +1,159 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	pb "gopkg.in/cheggaaa/pb.v1"
+)
+
+// Throughput is averaged over this window when estimating the time remaining.
+const xferRateWindow = 5 * time.Second
+
+// When stdout is not a terminal, a status line is printed at this interval.
+const xferPrintInterval = 2 * time.Second
+
+type xferSample struct {
+	when  time.Time
+	bytes int
+}
+
+// Reports the progress of a transfer of known size.  If stdout is a terminal,
+// progress is drawn as a bar that is updated in place; otherwise a status line
+// is printed periodically so that captured output stays readable.
+type xferProgress struct {
+	mtx       sync.Mutex
+	total     int
+	cur       int
+	samples   []xferSample
+	seeded    bool
+	bar       *pb.ProgressBar
+	lastPrint time.Time
+}
+
+func stdoutIsTty() bool {
+	fi, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+func newXferProgress(total int) *xferProgress {
+	p := &xferProgress{
+		total:   total,
+		samples: []xferSample{{time.Now(), 0}},
+	}
+
+	if stdoutIsTty() {
+		p.bar = pb.New(total)
+		p.bar.SetUnits(pb.U_BYTES)
+		p.bar.ShowSpeed = false
+		p.bar.ShowTimeLeft = false
+		p.bar.Start()
+	}
+
+	return p
+}
+
+// Calculates the throughput, in bytes per second, over the sample window.
+func (p *xferProgress) rate() float64 {
+	first := p.samples[0]
+	last := p.samples[len(p.samples)-1]
+
+	secs := last.when.Sub(first.when).Seconds()
+	if secs <= 0 {
+		return 0
+	}
+
+	return float64(last.bytes-first.bytes) / secs
+}
+
+func (p *xferProgress) rateStr() string {
+	rate := p.rate()
+	if rate <= 0 {
+		return "-- KB/s ETA --"
+	}
+
+	eta := time.Duration(float64(p.total-p.cur) / rate * float64(time.Second))
+	return fmt.Sprintf("%.1f KB/s ETA %s", rate/1024, eta.Round(time.Second))
+}
+
+func (p *xferProgress) printLine() {
+	pct := 100
+	if p.total > 0 {
+		pct = p.cur * 100 / p.total
+	}
+
+	fmt.Printf("%d/%d bytes (%d%%) %s\n", p.cur, p.total, pct, p.rateStr())
+	p.lastPrint = time.Now()
+}
+
+// Records that the first cur bytes of the transfer have completed.
+func (p *xferProgress) Set(cur int) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if cur <= p.cur {
+		return
+	}
+	p.cur = cur
+
+	now := time.Now()
+	if !p.seeded {
+		// The first offset seen becomes the start of the sample window, so
+		// that bytes already present on the other side (e.g., when a
+		// transfer is resumed) are not counted as transferred.
+		p.samples = []xferSample{{now, cur}}
+		p.seeded = true
+	} else {
+		p.samples = append(p.samples, xferSample{now, cur})
+	}
+
+	// Discard the oldest sample as long as the next one still spans the full
+	// averaging window.
+	for len(p.samples) > 2 && now.Sub(p.samples[1].when) >= xferRateWindow {
+		p.samples = p.samples[1:]
+	}
+
+	if p.bar != nil {
+		p.bar.Postfix(" " + p.rateStr())
+		p.bar.Set(cur)
+	} else if now.Sub(p.lastPrint) >= xferPrintInterval {
+		p.printLine()
+	}
+}
+
+// Completes the progress report.
+func (p *xferProgress) Finish() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.bar != nil {
+		p.bar.Finish()
+	} else {
+		p.printLine()
+	}
+}
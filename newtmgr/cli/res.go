@@ -38,7 +38,6 @@ import (
 )
 
 var details bool
-var resJson bool
 var resInt bool
 var resJsonFilename string
 var resRawFilename string
@@ -259,7 +258,7 @@ func parsePayload(args []string) ([]byte, error) {
 		return nil, nil
 	}
 
-	if resJson {
+	if nmutil.JsonOutput {
 		val, err = parsePayloadJson(args[0])
 		if err != nil {
 			return nil, err
@@ -409,13 +408,14 @@ func resCmd() *cobra.Command {
 	resCmd := &cobra.Command{
 		Use:   "res <op> <path> <k=v> [k=v] [k=v]",
 		Short: "Access a CoAP resource on a device",
-		Run:   runResCmd,
+		Long: "Access a CoAP resource on a device.\n\n" +
+			"With the global --json flag, the message body is given as a " +
+			"single JSON\nstring instead of k=v pairs.",
+		Run: runResCmd,
 	}
 
 	resCmd.PersistentFlags().BoolVarP(&details, "details", "d", false,
 		"Show more details about the CoAP response")
-	resCmd.PersistentFlags().BoolVarP(&resJson, "json", "j", false,
-		"Accept a JSON string for the CoAP message body (not `k=v` pairs)")
 	resCmd.PersistentFlags().StringVarP(&resJsonFilename, "jsonfile", "J", "",
 		"Name of file containing JSON for the CoAP message body")
 	resCmd.PersistentFlags().StringVarP(&resRawFilename, "rawfile", "R", "",
@@ -20,37 +20,187 @@
 package cli
 
 import (
+	"bufio"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"mynewt.apache.org/newt/util"
 	"mynewt.apache.org/newtmgr/newtmgr/nmutil"
+	"mynewt.apache.org/newtmgr/nmxact/nmp"
+	"mynewt.apache.org/newtmgr/nmxact/nmxutil"
+	"mynewt.apache.org/newtmgr/nmxact/sesn"
 	"mynewt.apache.org/newtmgr/nmxact/xact"
 )
 
-func resetRunCmd(cmd *cobra.Command, args []string) {
-	s, err := GetSesn()
+var resetConfirm bool
+var resetForce bool
+var resetReason bool
+
+// Name of the log in which Mynewt's reboot package records the cause of each
+// reset.
+const resetReasonLog = "reboot_log"
+
+// How long to wait for the device to come back up before reconnecting.
+const resetReconnectDelay = 2 * time.Second
+
+func stdinIsTty() bool {
+	fi, err := os.Stdin.Stat()
 	if err != nil {
-		nmUsage(nil, err)
+		return false
 	}
 
-	c := xact.NewResetCmd()
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Asks the user to confirm the reset.  Anything other than "y" or "yes"
+// declines.
+func resetPrompt(name string) bool {
+	if name == "" {
+		fmt.Printf("Reset device? [y/N] ")
+	} else {
+		fmt.Printf("Reset device %s? [y/N] ", name)
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Printf("\n")
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// Extracts the reset reason from a reboot log entry.  CBOR entries carry the
+// reason in the "rsn" field; string entries are returned as is.
+func resetReasonText(entry nmp.LogEntry) string {
+	switch entry.Type {
+	case nmp.LOG_ENTRY_TYPE_CBOR:
+		cm, err := nmxutil.DecodeCborMap(entry.Msg)
+		if err != nil {
+			break
+		}
+		if rsn, ok := cm["rsn"]; ok {
+			return fmt.Sprintf("%v", rsn)
+		}
+		if txt, err := logCborMsgText(entry.Msg); err == nil {
+			return txt
+		}
+
+	case nmp.LOG_ENTRY_TYPE_STRING:
+		return string(entry.Msg)
+	}
+
+	return "(undecodable entry)"
+}
+
+// Reads the most recent entry from the device's reboot log.
+func resetReadReason(s sesn.Sesn) (string, error) {
+	c := xact.NewLogShowFullCmd()
 	c.SetTxOptions(nmutil.TxOptions())
+	c.Name = resetReasonLog
+
+	var last *nmp.LogEntry
+	c.ProgressCb = func(_ *xact.LogShowFullCmd, rsp *nmp.LogShowRsp) {
+		for _, log := range rsp.Logs {
+			for i := range log.Entries {
+				if last == nil || log.Entries[i].Index >= last.Index {
+					last = &log.Entries[i]
+				}
+			}
+		}
+	}
 
 	if _, err := c.Run(s); err != nil {
+		return "", util.ChildNewtError(err)
+	}
+
+	if last == nil {
+		return "", util.FmtNewtError("%s is empty", resetReasonLog)
+	}
+
+	return resetReasonText(*last), nil
+}
+
+// Closes the current session, waits for the device to restart, and reopens
+// it.
+func resetReconnect() (sesn.Sesn, error) {
+	if globalSesn != nil {
+		globalSesn.Close()
+		globalSesn = nil
+	}
+
+	time.Sleep(resetReconnectDelay)
+
+	return GetSesn()
+}
+
+func resetRunCmd(cmd *cobra.Command, args []string) {
+	if !resetForce && (resetConfirm || stdinIsTty()) {
+		if !resetPrompt(nmutil.ConnProfile) {
+			fmt.Printf("Reset cancelled\n")
+			return
+		}
+	}
+
+	c, err := GetClient()
+	if err != nil {
+		nmUsage(nil, err)
+	}
+
+	if err := c.Reset(); err != nil {
 		nmUsage(nil, util.ChildNewtError(err))
 	}
 
 	fmt.Printf("Done\n")
+
+	if !resetReason {
+		return
+	}
+
+	s, err := resetReconnect()
+	if err != nil {
+		fmt.Printf("Reset reason: unavailable (reconnect failed: %s)\n",
+			err.Error())
+		return
+	}
+
+	rsn, err := resetReadReason(s)
+	if err != nil {
+		fmt.Printf("Reset reason: unavailable (%s)\n", err.Error())
+		return
+	}
+
+	fmt.Printf("Reset reason: %s\n", rsn)
 }
 
 func resetCmd() *cobra.Command {
+	resetHelpText := "Perform a soft reset of a device.\n\n" +
+		"When run from a terminal, or with --confirm, the reset must be " +
+		"confirmed\ninteractively; --force skips the prompt.  With --reason, " +
+		"newtmgr reconnects\nafter the reset and reports the most recent " +
+		"entry in the device's\n" + resetReasonLog + ", if it has one."
+
 	resetCmd := &cobra.Command{
 		Use:   "reset -c <conn_profile>",
 		Short: "Perform a soft reset of a device",
+		Long:  resetHelpText,
 		Run:   resetRunCmd,
 	}
+	resetCmd.Flags().BoolVar(&resetConfirm, "confirm", false,
+		"Prompt for confirmation even when stdin is not a terminal")
+	resetCmd.Flags().BoolVar(&resetForce, "force", false,
+		"Reset without prompting")
+	resetCmd.Flags().BoolVar(&resetReason, "reason", false,
+		"Reconnect after the reset and read back the reset reason")
 
 	return resetCmd
 }
@@ -77,6 +77,11 @@ func runListCmd(cmd *cobra.Command, args []string) {
 	}
 
 	sres := res.(*xact.RunListResult)
+	if nmutil.JsonOutput {
+		printJson(sres.Rsp)
+		return
+	}
+
 	if sres.Rsp.Rc != 0 {
 		fmt.Printf("Error: %d\n", sres.Rsp.Rc)
 		return
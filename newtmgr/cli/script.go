@@ -0,0 +1,152 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/flynn-archive/go-shlex"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"mynewt.apache.org/newt/util"
+	"mynewt.apache.org/newtmgr/newtmgr/nmutil"
+)
+
+type scriptLine struct {
+	num  int
+	argv []string
+}
+
+// Parses a script file into a sequence of command lines.  Blank lines and
+// lines starting with '#' are ignored.  A leading executable name on a line is
+// optional and is stripped.
+func readScript(filename string) ([]scriptLine, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, util.ChildNewtError(err)
+	}
+	defer f.Close()
+
+	var lines []scriptLine
+
+	scanner := bufio.NewScanner(f)
+	for num := 1; scanner.Scan(); num++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		argv, err := shlex.Split(text)
+		if err != nil {
+			return nil, util.FmtNewtError("%s:%d: %s",
+				filename, num, err.Error())
+		}
+		if len(argv) > 0 && argv[0] == nmutil.ToolInfo.ExeName {
+			argv = argv[1:]
+		}
+		if len(argv) == 0 {
+			continue
+		}
+
+		lines = append(lines, scriptLine{num, argv})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, util.ChildNewtError(err)
+	}
+
+	return lines, nil
+}
+
+// Records the global flags explicitly set on the script command line.
+func scriptOuterFlags(cmd *cobra.Command) map[string]string {
+	outer := map[string]string{}
+	cmd.Root().PersistentFlags().Visit(func(f *pflag.Flag) {
+		outer[f.Name] = f.Value.String()
+	})
+
+	return outer
+}
+
+func scriptRunCmd(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		nmUsage(cmd, nil)
+	}
+
+	lines, err := readScript(args[0])
+	if err != nil {
+		nmUsage(nil, err)
+	}
+
+	// Open the session before running any commands so that they all share it.
+	// Connection flags specified on individual script lines have no effect.
+	if _, err := GetSesn(); err != nil {
+		nmUsage(nil, err)
+	}
+
+	// Each line is executed against a fresh command tree.  Registering its
+	// flags resets the bound globals to their defaults, so the flags given to
+	// the script command itself are reapplied before every line.
+	outer := scriptOuterFlags(cmd)
+
+	for _, l := range lines {
+		fmt.Printf("> %s\n", strings.Join(l.argv, " "))
+
+		c := Commands()
+		if sub, _, err := c.Find(l.argv); err == nil && sub.Name() == "script" {
+			nmUsage(nil, util.FmtNewtError("%s:%d: scripts cannot be nested",
+				args[0], l.num))
+		}
+		for name, val := range outer {
+			if err := c.PersistentFlags().Set(name, val); err != nil {
+				nmUsage(nil, util.ChildNewtError(err))
+			}
+		}
+
+		c.SetArgs(l.argv)
+		if err := c.Execute(); err != nil {
+			nmUsage(nil, util.FmtNewtError("%s:%d: %s",
+				args[0], l.num, err.Error()))
+		}
+	}
+}
+
+func scriptCmd() *cobra.Command {
+	scriptHelpText := "Run a sequence of " + nmutil.ToolInfo.ShortName +
+		" commands read from a file,\none command per line, over a single " +
+		"connection.  Lines are split into\narguments like a shell command " +
+		"line.  Blank lines and lines starting\nwith '#' are ignored.  " +
+		"Execution stops at the first failing command.\n"
+
+	scriptEx := "  " + nmutil.ToolInfo.ExeName + " -c olimex script provision.txt\n"
+
+	scriptCmd := &cobra.Command{
+		Use:     "script <script-file> -c <conn_profile>",
+		Short:   "Run commands from a file over a single connection",
+		Long:    scriptHelpText,
+		Example: scriptEx,
+		Run:     scriptRunCmd,
+	}
+
+	return scriptCmd
+}
@@ -22,11 +22,14 @@ package cli
 import (
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"mynewt.apache.org/newt/util"
 	"mynewt.apache.org/newtmgr/newtmgr/nmutil"
+	"mynewt.apache.org/newtmgr/nmxact/nmp"
+	"mynewt.apache.org/newtmgr/nmxact/sesn"
 	"mynewt.apache.org/newtmgr/nmxact/xact"
 )
 
@@ -45,7 +48,9 @@ func statsListRunCmd(cmd *cobra.Command, args []string) {
 	}
 
 	sres := res.(*xact.StatListResult)
-	if sres.Rsp.Rc != 0 {
+	if nmutil.JsonOutput {
+		printJson(sres.Rsp)
+	} else if sres.Rsp.Rc != 0 {
 		fmt.Printf("Error: %d\n", sres.Rsp.Rc)
 	} else if len(sres.Rsp.List) == 0 {
 		fmt.Printf("stat groups: none\n")
@@ -63,54 +68,160 @@ func statsListRunCmd(cmd *cobra.Command, args []string) {
 	}
 }
 
+var statWatch float64
+var statCount int
+
+func statRead(s sesn.Sesn, name string) (*nmp.StatReadRsp, error) {
+	c := xact.NewStatReadCmd()
+	c.SetTxOptions(nmutil.TxOptions())
+	c.Name = name
+
+	res, err := c.Run(s)
+	if err != nil {
+		return nil, util.ChildNewtError(err)
+	}
+
+	return res.(*xact.StatReadResult).Rsp, nil
+}
+
+// Converts a decoded stat value to an integer.
+func statFieldInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case uint64:
+		return int64(n), true
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// Prints a stat group.  If prev is non-nil, each field is followed by its
+// change since the previous sample.
+func statPrintRsp(rsp *nmp.StatReadRsp, prev map[string]interface{}) {
+	if nmutil.JsonOutput {
+		printJson(rsp)
+		return
+	}
+
+	if rsp.Rc != 0 {
+		fmt.Printf("Error: %d\n", rsp.Rc)
+		return
+	}
+
+	fmt.Printf("stat group: %s\n", rsp.Name)
+	if len(rsp.Fields) == 0 {
+		fmt.Printf("    (empty)\n")
+		return
+	}
+
+	names := make([]string, 0, len(rsp.Fields))
+	for k := range rsp.Fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		if prev == nil {
+			fmt.Printf("%10d %s\n", rsp.Fields[n], n)
+			continue
+		}
+
+		cur, ok1 := statFieldInt(rsp.Fields[n])
+		old, ok2 := statFieldInt(prev[n])
+		if ok1 && ok2 {
+			fmt.Printf("%10d %+10d %s\n", cur, cur-old, n)
+		} else {
+			fmt.Printf("%10d %10s %s\n", rsp.Fields[n], "", n)
+		}
+	}
+}
+
+// Rereads a stat group at the watch interval, printing each sample with the
+// change since the previous one.  On a terminal, the display is redrawn in
+// place.
+func statWatchRun(s sesn.Sesn, name string) {
+	interval := time.Duration(statWatch * float64(time.Second))
+	tty := stdoutIsTty() && !nmutil.JsonOutput
+
+	var prev map[string]interface{}
+	for i := 0; statCount <= 0 || i < statCount; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+
+		rsp, err := statRead(s, name)
+		if err != nil {
+			nmUsage(nil, err)
+		}
+
+		if tty {
+			fmt.Printf("\033[H\033[2J")
+		}
+		if !nmutil.JsonOutput {
+			fmt.Printf("%s (sample %d)\n",
+				time.Now().Format("15:04:05"), i+1)
+		}
+		statPrintRsp(rsp, prev)
+		if !tty && !nmutil.JsonOutput {
+			fmt.Printf("\n")
+		}
+
+		if rsp.Rc == 0 {
+			prev = rsp.Fields
+		}
+	}
+}
+
 func statsRunCmd(cmd *cobra.Command, args []string) {
 	if len(args) < 1 {
 		nmUsage(cmd, nil)
 	}
+	if statWatch < 0 {
+		nmUsage(cmd, util.FmtNewtError("Invalid watch interval: %g",
+			statWatch))
+	}
 
 	s, err := GetSesn()
 	if err != nil {
 		nmUsage(nil, err)
 	}
 
-	c := xact.NewStatReadCmd()
-	c.SetTxOptions(nmutil.TxOptions())
-	c.Name = args[0]
-
-	res, err := c.Run(s)
-	if err != nil {
-		nmUsage(nil, util.ChildNewtError(err))
+	if statWatch > 0 {
+		statWatchRun(s, args[0])
+		return
 	}
 
-	sres := res.(*xact.StatReadResult)
-	if sres.Rsp.Rc != 0 {
-		fmt.Printf("Error: %d\n", sres.Rsp.Rc)
-	} else {
-		fmt.Printf("stat group: %s\n", sres.Rsp.Name)
-		if len(sres.Rsp.Fields) == 0 {
-			fmt.Printf("    (empty)\n")
-		} else {
-			names := make([]string, 0, len(sres.Rsp.Fields))
-			for k, _ := range sres.Rsp.Fields {
-				names = append(names, k)
-			}
-			sort.Strings(names)
-
-			for _, n := range names {
-				fmt.Printf("%10d %s\n", sres.Rsp.Fields[n], n)
-			}
-		}
+	rsp, err := statRead(s, args[0])
+	if err != nil {
+		nmUsage(nil, err)
 	}
+	statPrintRsp(rsp, nil)
 }
 
 func statsCmd() *cobra.Command {
-	statsHelpText := "Read statistics for the specified stats_name from a device"
+	statsHelpText := "Read statistics for the specified stats_name from " +
+		"a device.\nWith --watch, the group is reread at the given interval and each " +
+		"value\nis shown with its change since the previous sample."
+	statsEx := nmutil.ToolInfo.ExeName + " -c olimex stat ble_ll\n"
+	statsEx += nmutil.ToolInfo.ExeName +
+		" -c olimex stat ble_ll --watch 5 --count 12\n"
+
 	statsCmd := &cobra.Command{
-		Use:   "stat <stats_name> -c <conn_profile>",
-		Short: "Read statistics from a device",
-		Long:  statsHelpText,
-		Run:   statsRunCmd,
+		Use:     "stat <stats_name> -c <conn_profile>",
+		Short:   "Read statistics from a device",
+		Long:    statsHelpText,
+		Example: statsEx,
+		Run:     statsRunCmd,
 	}
+	statsCmd.Flags().Float64Var(&statWatch, "watch", 0,
+		"Reread the group every this many seconds")
+	statsCmd.Flags().IntVar(&statCount, "count", 0,
+		"With --watch, stop after this many samples (default: unlimited)")
 
 	ListCmd := &cobra.Command{
 		Use:   "list -c <conn_profile>",
@@ -22,36 +22,55 @@ package cli
 import (
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"mynewt.apache.org/newt/util"
 	"mynewt.apache.org/newtmgr/newtmgr/nmutil"
+	"mynewt.apache.org/newtmgr/nmxact/nmp"
+	"mynewt.apache.org/newtmgr/nmxact/sesn"
 	"mynewt.apache.org/newtmgr/nmxact/xact"
 )
 
-func taskStatRunCmd(cmd *cobra.Command, args []string) {
-	s, err := GetSesn()
-	if err != nil {
-		nmUsage(nil, err)
-	}
+var taskStatCpu float64
 
+// Reads a taskstat response.  A nonzero rc is left for the caller to handle.
+func taskStatRead(s sesn.Sesn) (*nmp.TaskStatRsp, error) {
 	c := xact.NewTaskStatCmd()
 	c.SetTxOptions(nmutil.TxOptions())
 
 	res, err := c.Run(s)
 	if err != nil {
-		nmUsage(nil, util.ChildNewtError(err))
+		return nil, util.ChildNewtError(err)
 	}
 
-	sres := res.(*xact.TaskStatResult)
-	if sres.Rsp.Rc != 0 {
-		fmt.Printf("Error: %d\n", sres.Rsp.Rc)
-		return
+	return res.(*xact.TaskStatResult).Rsp, nil
+}
+
+// Reads a taskstat sample for usage calculations; a nonzero rc is an error.
+func taskStatSample(s sesn.Sesn) (*nmp.TaskStatRsp, error) {
+	rsp, err := taskStatRead(s)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.Rc != 0 {
+		return nil, util.FmtNewtError("taskstat failed; rc=%d", rsp.Rc)
 	}
 
-	names := make([]string, 0, len(sres.Rsp.Tasks))
-	for k, _ := range sres.Rsp.Tasks {
+	return rsp, nil
+}
+
+// Computes the difference between two readings of a device counter.  The
+// counters are 32 bits wide, so the subtraction is done modulo 2^32 to
+// survive a wrap between samples.
+func taskStatDelta(after int, before int) int {
+	return int(uint32(after) - uint32(before))
+}
+
+func taskStatPrintRaw(rsp *nmp.TaskStatRsp) {
+	names := make([]string, 0, len(rsp.Tasks))
+	for k := range rsp.Tasks {
 		names = append(names, k)
 	}
 	sort.Strings(names)
@@ -60,7 +79,7 @@ func taskStatRunCmd(cmd *cobra.Command, args []string) {
 		"task", "pri", "tid", "runtime", "csw", "stksz",
 		"stkuse", "last_checkin", "next_checkin")
 	for _, n := range names {
-		t := sres.Rsp.Tasks[n]
+		t := rsp.Tasks[n]
 		fmt.Printf("  %8s\t%3d %3d %8d %8d %8d %8d %8d %8d\n",
 			n,
 			t["prio"],
@@ -74,12 +93,150 @@ func taskStatRunCmd(cmd *cobra.Command, args []string) {
 	}
 }
 
+// Per-task usage computed from two taskstat samples.
+type taskUsage struct {
+	Name   string  `json:"name"`
+	Prio   int     `json:"prio"`
+	CpuPct float64 `json:"cpu_pct"`
+	StkPct float64 `json:"stack_pct"`
+	CswHz  float64 `json:"csw_per_sec"`
+}
+
+// Computes per-task CPU share, stack high-water and context-switch rate from
+// two samples taken dur apart.  CPU share is each task's runtime delta as a
+// fraction of the total across all tasks, so it does not depend on the
+// device's tick rate.  Tasks absent from either sample are skipped.
+func taskStatUsage(before *nmp.TaskStatRsp, after *nmp.TaskStatRsp,
+	dur time.Duration) []taskUsage {
+
+	total := 0
+	for n, t := range after.Tasks {
+		if b, ok := before.Tasks[n]; ok {
+			total += taskStatDelta(t["runtime"], b["runtime"])
+		}
+	}
+
+	usage := []taskUsage{}
+	for n, t := range after.Tasks {
+		b, ok := before.Tasks[n]
+		if !ok {
+			continue
+		}
+
+		u := taskUsage{
+			Name: n,
+			Prio: t["prio"],
+		}
+		if total > 0 {
+			u.CpuPct = float64(taskStatDelta(t["runtime"], b["runtime"])) *
+				100 / float64(total)
+		}
+		if t["stksiz"] > 0 {
+			u.StkPct = float64(t["stkuse"]) * 100 / float64(t["stksiz"])
+		}
+		if dur > 0 {
+			u.CswHz = float64(taskStatDelta(t["cswcnt"], b["cswcnt"])) /
+				dur.Seconds()
+		}
+
+		usage = append(usage, u)
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].CpuPct != usage[j].CpuPct {
+			return usage[i].CpuPct > usage[j].CpuPct
+		}
+		return usage[i].Name < usage[j].Name
+	})
+
+	return usage
+}
+
+func taskStatCpuRun(s sesn.Sesn) error {
+	interval := time.Duration(taskStatCpu * float64(time.Second))
+
+	before, err := taskStatSample(s)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+
+	time.Sleep(interval)
+
+	after, err := taskStatSample(s)
+	if err != nil {
+		return err
+	}
+
+	usage := taskStatUsage(before, after, time.Since(start))
+	if nmutil.JsonOutput {
+		printJson(usage)
+		return nil
+	}
+
+	fmt.Printf("  %8s\t%3s %7s %7s %9s\n",
+		"task", "pri", "cpu%", "stack%", "csw/s")
+	for _, u := range usage {
+		fmt.Printf("  %8s\t%3d %7.1f %7.1f %9.1f\n",
+			u.Name, u.Prio, u.CpuPct, u.StkPct, u.CswHz)
+	}
+
+	return nil
+}
+
+func taskStatRunCmd(cmd *cobra.Command, args []string) {
+	if taskStatCpu < 0 {
+		nmUsage(cmd, util.FmtNewtError("Invalid sample interval: %g",
+			taskStatCpu))
+	}
+
+	s, err := GetSesn()
+	if err != nil {
+		nmUsage(nil, err)
+	}
+
+	if taskStatCpu > 0 {
+		if err := taskStatCpuRun(s); err != nil {
+			nmUsage(nil, err)
+		}
+		return
+	}
+
+	rsp, err := taskStatRead(s)
+	if err != nil {
+		nmUsage(nil, err)
+	}
+	if rsp.Rc != 0 {
+		fmt.Printf("Error: %d\n", rsp.Rc)
+		return
+	}
+
+	if nmutil.JsonOutput {
+		printJson(rsp)
+		return
+	}
+
+	taskStatPrintRaw(rsp)
+}
+
 func taskStatCmd() *cobra.Command {
+	taskStatHelpText := "Read task statistics from a device.\n\n" +
+		"With --cpu, two samples are taken the given number of seconds " +
+		"apart and\neach task's CPU share, stack high-water mark and " +
+		"context-switch rate\nare printed, busiest task first."
+
+	taskStatEx := nmutil.ToolInfo.ExeName + " -c olimex taskstat\n"
+	taskStatEx += nmutil.ToolInfo.ExeName + " -c olimex taskstat --cpu 5\n"
+
 	taskStatCmd := &cobra.Command{
-		Use:   "taskstat -c <conn_profile>",
-		Short: "Read task statistics from a device",
-		Run:   taskStatRunCmd,
+		Use:     "taskstat -c <conn_profile>",
+		Short:   "Read task statistics from a device",
+		Long:    taskStatHelpText,
+		Example: taskStatEx,
+		Run:     taskStatRunCmd,
 	}
+	taskStatCmd.Flags().Float64Var(&taskStatCpu, "cpu", 0,
+		"Sample twice this many seconds apart and show per-task usage")
 
 	return taskStatCmd
 }
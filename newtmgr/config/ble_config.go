@@ -148,6 +148,14 @@ func FillSesnCfg(bx *nmble.BleXport, bc *BleConfig, sc *sesn.SesnCfg) error {
 
 	sc.Ble.Central.ConnTimeout =
 		time.Duration(bc.ConnTimeout*1000000000) * time.Nanosecond
+	if nmutil.ConnectRetries >= 0 {
+		sc.Ble.Central.ConnTries = nmutil.ConnectRetries + 1
+		sc.Ble.Central.ConnRetryAll = true
+	}
+	if nmutil.ConnectDelay >= 0 {
+		sc.Ble.Central.ConnRetryDelay =
+			time.Duration(nmutil.ConnectDelay * float64(time.Second))
+	}
 	sc.Ble.CloseTimeout = 10000 * time.Millisecond
 
 	sc.Ble.WriteRsp = nmutil.BleWriteRsp
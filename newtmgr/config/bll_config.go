@@ -127,6 +127,14 @@ func BuildBllSesnCfg(bc *BllConfig) (bll.BllSesnCfg, error) {
 
 	sc.WriteRsp = nmutil.BleWriteRsp
 	sc.ConnTimeout = time.Duration(bc.ConnTimeout*1000000000) * time.Nanosecond
+	if nmutil.ConnectRetries >= 0 {
+		sc.ConnTries = nmutil.ConnectRetries + 1
+		sc.ConnRetryAll = true
+	}
+	if nmutil.ConnectDelay >= 0 {
+		sc.ConnRetryDelay =
+			time.Duration(nmutil.ConnectDelay * float64(time.Second))
+	}
 
 	return sc, nil
 }
@@ -29,6 +29,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"mynewt.apache.org/newt/util"
 	"mynewt.apache.org/newtmgr/newtmgr/nmutil"
@@ -44,11 +45,42 @@ type ConnProfile struct {
 	Name       string   `json:"MyName"`
 	Type       ConnType `json:"MyType"`
 	ConnString string   `json:"MyConnString"`
+
+	// Transaction defaults; zero means unspecified.
+	Timeout float64 `json:"MyTimeout,omitempty"`
+	Tries   int     `json:"MyTries,omitempty"`
+
+	// Default MTU for transports that have one; an mtu key in the connstring
+	// takes precedence.
+	Mtu int `json:"MyMtu,omitempty"`
 }
 
 func (p *ConnProfile) String() string {
-	return fmt.Sprintf("name=%s type=%s connstring=%s",
+	s := fmt.Sprintf("name=%s type=%s connstring=%s",
 		p.Name, ConnTypeToString(p.Type), p.ConnString)
+
+	if p.Timeout > 0 {
+		s += fmt.Sprintf(" timeout=%g", p.Timeout)
+	}
+	if p.Tries > 0 {
+		s += fmt.Sprintf(" tries=%d", p.Tries)
+	}
+	if p.Mtu > 0 {
+		s += fmt.Sprintf(" mtu=%d", p.Mtu)
+	}
+
+	return s
+}
+
+// Indicates whether a key=value style connstring specifies the given key.
+func ConnStringHasKey(cs string, key string) bool {
+	for _, p := range strings.Split(cs, ",") {
+		if strings.HasPrefix(p, key+"=") {
+			return true
+		}
+	}
+
+	return false
 }
 
 const (
@@ -62,6 +94,7 @@ const (
 	CONN_TYPE_UDP_PLAIN
 	CONN_TYPE_UDP_OIC
 	CONN_TYPE_MTECH_LORA_OIC
+	CONN_TYPE_TCP_PLAIN
 )
 
 var connTypeNameMap = map[ConnType]string{
@@ -74,6 +107,7 @@ var connTypeNameMap = map[ConnType]string{
 	CONN_TYPE_UDP_PLAIN:      "udp",
 	CONN_TYPE_UDP_OIC:        "oic_udp",
 	CONN_TYPE_MTECH_LORA_OIC: "oic_mtech",
+	CONN_TYPE_TCP_PLAIN:      "tcp",
 	CONN_TYPE_NONE:           "???",
 }
 
@@ -91,6 +125,62 @@ func ConnTypeFromString(s string) (ConnType, error) {
 	return ConnType(0), util.FmtNewtError("Invalid connection type: %s", s)
 }
 
+// Management protocol framings.  "smp" is the plain header used by mcumgr
+// and by the non-OIC connection types; "oic" wraps each request in a CoAP
+// message.
+const (
+	CONN_PROTO_SMP = "smp"
+	CONN_PROTO_OIC = "oic"
+)
+
+// Pairs of connection types that share a transport but differ in framing.
+var connTypeProtoPairs = [][2]ConnType{
+	{CONN_TYPE_SERIAL_PLAIN, CONN_TYPE_SERIAL_OIC},
+	{CONN_TYPE_BLL_PLAIN, CONN_TYPE_BLL_OIC},
+	{CONN_TYPE_BLE_PLAIN, CONN_TYPE_BLE_OIC},
+	{CONN_TYPE_UDP_PLAIN, CONN_TYPE_UDP_OIC},
+}
+
+// Indicates which framing the specified connection type uses.
+func ConnTypeProto(ct ConnType) string {
+	switch ct {
+	case CONN_TYPE_SERIAL_OIC, CONN_TYPE_BLL_OIC, CONN_TYPE_BLE_OIC,
+		CONN_TYPE_UDP_OIC, CONN_TYPE_MTECH_LORA_OIC:
+
+		return CONN_PROTO_OIC
+	default:
+		return CONN_PROTO_SMP
+	}
+}
+
+// Returns the connection type that uses the same transport as ct with the
+// specified framing.
+func ConnTypeWithProto(ct ConnType, proto string) (ConnType, error) {
+	proto = strings.ToLower(proto)
+	if proto != CONN_PROTO_SMP && proto != CONN_PROTO_OIC {
+		return ct, util.FmtNewtError(
+			"Invalid protocol: %s (expected smp or oic)", proto)
+	}
+
+	if ConnTypeProto(ct) == proto {
+		return ct, nil
+	}
+
+	for _, pair := range connTypeProtoPairs {
+		if pair[0] == ct || pair[1] == ct {
+			if proto == CONN_PROTO_SMP {
+				return pair[0], nil
+			} else {
+				return pair[1], nil
+			}
+		}
+	}
+
+	return ct, util.FmtNewtError(
+		"Connection type %s does not support protocol %s",
+		ConnTypeToString(ct), proto)
+}
+
 func (t *ConnType) MarshalJSON() ([]byte, error) {
 	return json.Marshal(ConnTypeToString(*t))
 }
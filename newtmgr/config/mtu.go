@@ -0,0 +1,64 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package config
+
+import (
+	"mynewt.apache.org/newt/util"
+	"mynewt.apache.org/newtmgr/nmxact/nmp"
+	"mynewt.apache.org/newtmgr/nmxact/omp"
+)
+
+// Smallest amount of request data a packet must be able to carry for chunked
+// transfers (image and file upload) to make progress.
+const MIN_MTU_PAYLOAD = 64
+
+// Smallest usable MTU: room for the NMP header, the OMP framing, and a
+// minimal payload.
+const MIN_MTU = nmp.NMP_HDR_SIZE + omp.OMP_MSG_OVERHEAD + MIN_MTU_PAYLOAD
+
+// Verifies that an MTU is usable.  A maxMtu of 0 means the transport imposes
+// no upper bound.
+func CheckMtu(mtu int, maxMtu int) error {
+	if mtu < MIN_MTU {
+		return util.FmtNewtError("Invalid mtu: %d (minimum is %d)",
+			mtu, MIN_MTU)
+	}
+	if maxMtu > 0 && mtu > maxMtu {
+		return util.FmtNewtError("Invalid mtu: %d (maximum is %d)",
+			mtu, maxMtu)
+	}
+
+	return nil
+}
+
+// Indicates whether a connection profile's mtu setting is used by the given
+// connection type.
+func ConnTypeHasMtu(ct ConnType) bool {
+	switch ct {
+	case CONN_TYPE_SERIAL_PLAIN, CONN_TYPE_SERIAL_OIC,
+		CONN_TYPE_UDP_PLAIN, CONN_TYPE_UDP_OIC,
+		CONN_TYPE_TCP_PLAIN:
+
+		return true
+
+	default:
+		return false
+	}
+}
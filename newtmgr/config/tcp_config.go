@@ -0,0 +1,75 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"mynewt.apache.org/newt/util"
+	"mynewt.apache.org/newtmgr/nmxact/sesn"
+	"mynewt.apache.org/newtmgr/nmxact/tcp"
+)
+
+func einvalTcpConnString(f string, args ...interface{}) error {
+	suffix := fmt.Sprintf(f, args...)
+	return util.FmtNewtError("Invalid TCP connstring; %s", suffix)
+}
+
+// Parses a TCP connstring into a session configuration.  The connstring is
+// either a bare "[tcp://]host:port" or a comma-separated list of key=value
+// pairs ("addr=tcp://host:port,mtu=512").
+func FillTcpSesnCfg(cs string, sc *sesn.SesnCfg) error {
+	parts := strings.Split(cs, ",")
+	for _, p := range parts {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) == 1 {
+			kv = []string{"addr", kv[0]}
+		}
+
+		k := kv[0]
+		v := kv[1]
+
+		switch k {
+		case "addr":
+			sc.PeerSpec.Tcp = strings.TrimPrefix(v, "tcp://")
+
+		case "mtu":
+			mtu, err := strconv.Atoi(v)
+			if err != nil {
+				return einvalTcpConnString("Invalid mtu: %s", v)
+			}
+			if err := CheckMtu(mtu, tcp.MAX_PACKET_SIZE); err != nil {
+				return einvalTcpConnString("%s", err.Error())
+			}
+			sc.Tcp.Mtu = mtu
+
+		default:
+			return einvalTcpConnString("Unrecognized key: %s", k)
+		}
+	}
+
+	if sc.PeerSpec.Tcp == "" {
+		return einvalTcpConnString("Missing addr")
+	}
+
+	return nil
+}
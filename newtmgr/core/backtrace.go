@@ -0,0 +1,254 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package core
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"mynewt.apache.org/newt/util"
+)
+
+const (
+	COREDUMP_REG_SP = 13
+	COREDUMP_REG_LR = 14
+)
+
+// Limits on the stack scan performed when building a backtrace.
+const (
+	BT_MAX_FRAMES     = 32
+	BT_MAX_STACK_WORD = 1024
+)
+
+type Frame struct {
+	Addr uint32
+	Func string
+	File string
+	Line string
+}
+
+// Prints the frame in the style of a gdb backtrace line.
+func (f *Frame) BtLine(idx int) string {
+	s := fmt.Sprintf("#%-2d 0x%08x in %s ()", idx, f.Addr, f.Func)
+	if f.File != "" {
+		s += fmt.Sprintf(" at %s:%s", f.File, f.Line)
+	}
+	return s
+}
+
+type funcSym struct {
+	name string
+	addr uint32
+	size uint32
+}
+
+type symTab []funcSym
+
+func readSymTab(elfFilename string) (symTab, error) {
+	ef, err := elf.Open(elfFilename)
+	if err != nil {
+		return nil, util.FmtNewtError("Cannot open ELF file %s - %s",
+			elfFilename, err.Error())
+	}
+	defer ef.Close()
+
+	syms, err := ef.Symbols()
+	if err != nil {
+		return nil, util.FmtNewtError("Cannot read symbols from %s - %s",
+			elfFilename, err.Error())
+	}
+
+	var st symTab
+	for _, s := range syms {
+		if elf.ST_TYPE(s.Info) != elf.STT_FUNC || s.Value == 0 {
+			continue
+		}
+
+		// Thumb function addresses have the low bit set.
+		st = append(st, funcSym{
+			name: s.Name,
+			addr: uint32(s.Value) &^ 1,
+			size: uint32(s.Size),
+		})
+	}
+
+	sort.Slice(st, func(i, j int) bool { return st[i].addr < st[j].addr })
+	return st, nil
+}
+
+func (st symTab) lookup(addr uint32) (string, bool) {
+	idx := sort.Search(len(st), func(i int) bool { return st[i].addr > addr })
+	if idx == 0 {
+		return "", false
+	}
+
+	s := st[idx-1]
+	if s.size != 0 && addr >= s.addr+s.size {
+		return "", false
+	}
+	return s.name, true
+}
+
+// Reads a 32-bit word from the memory regions captured in the core.
+func (cc *CoreConvert) readWord(addr uint32) (uint32, bool) {
+	for idx, phdr := range cc.phdrs {
+		if phdr.Type != uint32(elf.PT_LOAD) {
+			continue
+		}
+		// Compare offsets rather than end addresses so that a segment near
+		// the top of the address space cannot wrap the check.
+		if addr < phdr.Vaddr || phdr.Filesz < 4 ||
+			addr-phdr.Vaddr > phdr.Filesz-4 {
+
+			continue
+		}
+
+		off := addr - phdr.Vaddr
+		return binary.LittleEndian.Uint32(cc.data[idx][off : off+4]), true
+	}
+
+	return 0, false
+}
+
+// Collects the code addresses making up the backtrace: the PC, the link
+// register, and any return addresses found by scanning the captured stack.
+// The stack scan is a heuristic; a word is reported if it looks like a Thumb
+// return address into a known function.
+func (cc *CoreConvert) btAddrs(st symTab) []uint32 {
+	var addrs []uint32
+
+	if pc, ok := cc.Pc(); ok {
+		addrs = append(addrs, pc)
+	}
+	if len(cc.Regs) > COREDUMP_REG_LR {
+		addrs = append(addrs, cc.Regs[COREDUMP_REG_LR])
+	}
+	if len(cc.Regs) <= COREDUMP_REG_SP {
+		return addrs
+	}
+
+	sp := cc.Regs[COREDUMP_REG_SP]
+	for i := 0; i < BT_MAX_STACK_WORD && len(addrs) < BT_MAX_FRAMES; i++ {
+		w, ok := cc.readWord(sp + uint32(i*4))
+		if !ok {
+			break
+		}
+		if w&1 == 0 {
+			continue
+		}
+		if _, ok := st.lookup(w &^ 1); !ok {
+			continue
+		}
+		if len(addrs) > 0 && w == addrs[len(addrs)-1] {
+			continue
+		}
+		addrs = append(addrs, w)
+	}
+
+	return addrs
+}
+
+// Runs addr2line to map each address to a file and line.  Returns nil if the
+// tool is unavailable.
+func addr2lineLookup(addr2line string, elfFilename string,
+	addrs []uint32) [][2]string {
+
+	if addr2line == "" || len(addrs) == 0 {
+		return nil
+	}
+
+	args := []string{"-e", elfFilename}
+	for _, a := range addrs {
+		args = append(args, fmt.Sprintf("0x%x", a))
+	}
+
+	out, err := exec.Command(addr2line, args...).Output()
+	if err != nil {
+		return nil
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != len(addrs) {
+		return nil
+	}
+
+	locs := make([][2]string, len(lines))
+	for i, l := range lines {
+		// Strip a trailing discriminator, e.g. "foo.c:12 (discriminator 1)".
+		if sp := strings.Index(l, " "); sp >= 0 {
+			l = l[:sp]
+		}
+		colon := strings.LastIndex(l, ":")
+		if colon < 0 || strings.HasPrefix(l, "??") {
+			continue
+		}
+		locs[i] = [2]string{l[:colon], l[colon+1:]}
+	}
+
+	return locs
+}
+
+// Builds a symbolicated backtrace for the core using the symbols in the
+// specified ELF file.  If addr2line is non-empty, it names the toolchain's
+// addr2line executable, which is used to find the file and line of each frame.
+func (cc *CoreConvert) Backtrace(elfFilename string,
+	addr2line string) ([]Frame, error) {
+
+	st, err := readSymTab(elfFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := cc.btAddrs(st)
+	if len(addrs) == 0 {
+		return nil, util.NewNewtError("No registers in core file")
+	}
+
+	// For return addresses, look up the call site rather than the
+	// instruction following it.
+	lookups := make([]uint32, len(addrs))
+	for i, a := range addrs {
+		lookups[i] = a &^ 1
+		if i > 0 && lookups[i] > 0 {
+			lookups[i]--
+		}
+	}
+
+	locs := addr2lineLookup(addr2line, elfFilename, lookups)
+
+	frames := make([]Frame, len(addrs))
+	for i, a := range addrs {
+		frames[i].Addr = a &^ 1
+		frames[i].Func = "??"
+		if name, ok := st.lookup(lookups[i]); ok {
+			frames[i].Func = name
+		}
+		if locs != nil {
+			frames[i].File = locs[i][0]
+			frames[i].Line = locs[i][1]
+		}
+	}
+
+	return frames, nil
+}
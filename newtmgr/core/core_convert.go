@@ -34,6 +34,7 @@ type CoreConvert struct {
 	Source    *os.File
 	Target    *os.File
 	ImageHash []byte
+	Regs      []uint32
 	elfHdr    *elf.Header32
 	phdrs     []*elf.Prog32
 	data      [][]byte
@@ -49,6 +50,24 @@ const (
 	COREDUMP_MAGIC = 0x690c47c3
 )
 
+// Index of the program counter in a register TLV.
+const COREDUMP_REG_PC = 15
+
+// Names of the registers in a register TLV, in the order the device stores
+// them.  This matches the ARM layout used in the ELF .reg note.
+var CoreRegNames = []string{
+	"r0", "r1", "r2", "r3", "r4", "r5", "r6", "r7",
+	"r8", "r9", "r10", "r11", "r12", "sp", "lr", "pc",
+	"xpsr",
+}
+
+func CoreRegName(idx int) string {
+	if idx < len(CoreRegNames) {
+		return CoreRegNames[idx]
+	}
+	return fmt.Sprintf("reg%d", idx)
+}
+
 type CoreDumpHdr struct {
 	Magic uint32
 	Size  uint32
@@ -246,11 +265,9 @@ func (cc *CoreConvert) setProgHdrOff() {
 	}
 }
 
-func (cc *CoreConvert) Convert() error {
-	if cc.Source == nil || cc.Target == nil {
-		return util.NewNewtError("Missing file parameters")
-	}
-
+// Reads the source corefile, collecting its image hash, registers, and
+// memory regions.
+func (cc *CoreConvert) parse() error {
 	err := cc.readHdr()
 	if err != nil {
 		return err
@@ -282,15 +299,28 @@ func (cc *CoreConvert) Convert() error {
 			if tlv.Len%4 != 0 {
 				return util.NewNewtError("Invalid register area size")
 			}
+			for off := 0; off < len(data_buf); off += 4 {
+				cc.Regs = append(cc.Regs,
+					binary.LittleEndian.Uint32(data_buf[off:off+4]))
+			}
 			cc.makeRegInfo(data_buf)
 		default:
 			return util.NewNewtError("Unknown TLV type")
 		}
 	}
-	cc.makeElfHdr()
-	if err != nil {
+
+	return nil
+}
+
+func (cc *CoreConvert) Convert() error {
+	if cc.Source == nil || cc.Target == nil {
+		return util.NewNewtError("Missing file parameters")
+	}
+
+	if err := cc.parse(); err != nil {
 		return err
 	}
+	cc.makeElfHdr()
 	cc.setProgHdrOff()
 
 	binary.Write(cc.Target, binary.LittleEndian, cc.elfHdr)
@@ -303,6 +333,35 @@ func (cc *CoreConvert) Convert() error {
 	return nil
 }
 
+// Returns the faulting program counter, or false if the corefile contains no
+// register TLV.
+func (cc *CoreConvert) Pc() (uint32, bool) {
+	if len(cc.Regs) <= COREDUMP_REG_PC {
+		return 0, false
+	}
+	return cc.Regs[COREDUMP_REG_PC], true
+}
+
+// Parses a raw corefile without converting it.
+func ReadFilename(srcFilename string) (*CoreConvert, error) {
+	coreConvert := NewCoreConvert()
+
+	var err error
+
+	coreConvert.Source, err = os.OpenFile(srcFilename, os.O_RDONLY, 0)
+	if err != nil {
+		return coreConvert, util.FmtNewtError("Cannot open file %s - %s",
+			srcFilename, err.Error())
+	}
+	defer coreConvert.Source.Close()
+
+	if err := coreConvert.parse(); err != nil {
+		return coreConvert, err
+	}
+
+	return coreConvert, nil
+}
+
 func ConvertFilenames(srcFilename string,
 	dstFilename string) (*CoreConvert, error) {
 
@@ -41,10 +41,14 @@ var ConnProfile string
 var DeviceName string
 var BleWriteRsp bool
 var ConnType string
+var ConnProto string
 var ConnString string
 var ConnExtra string
 var ToolInfo ToolInfoType
 var HciIdx int
+var JsonOutput bool
+var ConnectRetries int
+var ConnectDelay float64
 
 func TxOptions() sesn.TxOptions {
 	return sesn.TxOptions{
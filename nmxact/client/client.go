@@ -0,0 +1,341 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package client provides a simple blocking API for the most common
+// management operations.  It is a thin layer over the xact package: each
+// method runs a single xact command and converts a nonzero NMP status into an
+// error.  Use xact directly for operations or responses not covered here.
+package client
+
+import (
+	"fmt"
+
+	"mynewt.apache.org/newtmgr/nmxact/nmp"
+	"mynewt.apache.org/newtmgr/nmxact/sesn"
+	"mynewt.apache.org/newtmgr/nmxact/xact"
+	"mynewt.apache.org/newtmgr/nmxact/xport"
+)
+
+// Indicates that the device completed a request with a nonzero status.
+type RcError struct {
+	Op string
+	Rc int
+}
+
+func (e *RcError) Error() string {
+	return fmt.Sprintf("%s failed; rc=%d", e.Op, e.Rc)
+}
+
+func IsRc(err error) bool {
+	_, ok := err.(*RcError)
+	return ok
+}
+
+func checkRc(op string, rc int) error {
+	if rc != 0 {
+		return &RcError{Op: op, Rc: rc}
+	}
+	return nil
+}
+
+// Reports the number of image bytes acknowledged by the device.
+type ImageUploadProgressFn func(off int, total int)
+
+type Client struct {
+	Sesn sesn.Sesn
+
+	// Applied to every command the client runs.
+	TxOptions sesn.TxOptions
+}
+
+// Creates a client that uses an existing session.  The caller remains
+// responsible for opening and closing the session.
+func New(s sesn.Sesn) *Client {
+	return &Client{
+		Sesn:      s,
+		TxOptions: sesn.NewTxOptions(),
+	}
+}
+
+// Builds a session over the specified transport, opens it, and returns a
+// client that uses it.  The transport must already be started.
+func Connect(x xport.Xport, cfg sesn.SesnCfg) (*Client, error) {
+	s, err := x.BuildSesn(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Open(); err != nil {
+		return nil, err
+	}
+
+	return New(s), nil
+}
+
+// Closes the client's session.
+func (c *Client) Close() error {
+	return c.Sesn.Close()
+}
+
+func (c *Client) Echo(payload string) (string, error) {
+	cmd := xact.NewEchoCmd()
+	cmd.SetTxOptions(c.TxOptions)
+	cmd.Payload = payload
+
+	res, err := cmd.Run(c.Sesn)
+	if err != nil {
+		return "", err
+	}
+
+	rsp := res.(*xact.EchoResult).Rsp
+	if err := checkRc("echo", rsp.Rc); err != nil {
+		return "", err
+	}
+
+	return rsp.Payload, nil
+}
+
+func (c *Client) Reset() error {
+	cmd := xact.NewResetCmd()
+	cmd.SetTxOptions(c.TxOptions)
+
+	_, err := cmd.Run(c.Sesn)
+	return err
+}
+
+func (c *Client) ConfigRead(name string) (string, error) {
+	cmd := xact.NewConfigReadCmd()
+	cmd.SetTxOptions(c.TxOptions)
+	cmd.Name = name
+
+	res, err := cmd.Run(c.Sesn)
+	if err != nil {
+		return "", err
+	}
+
+	rsp := res.(*xact.ConfigReadResult).Rsp
+	if err := checkRc("config read", rsp.Rc); err != nil {
+		return "", err
+	}
+
+	return rsp.Val, nil
+}
+
+func (c *Client) ConfigWrite(name string, val string) error {
+	cmd := xact.NewConfigWriteCmd()
+	cmd.SetTxOptions(c.TxOptions)
+	cmd.Name = name
+	cmd.Val = val
+
+	res, err := cmd.Run(c.Sesn)
+	if err != nil {
+		return err
+	}
+
+	return checkRc("config write", res.(*xact.ConfigWriteResult).Rsp.Rc)
+}
+
+// Persists the device's current configuration.
+func (c *Client) ConfigSave() error {
+	cmd := xact.NewConfigWriteCmd()
+	cmd.SetTxOptions(c.TxOptions)
+	cmd.Save = true
+
+	res, err := cmd.Run(c.Sesn)
+	if err != nil {
+		return err
+	}
+
+	return checkRc("config save", res.(*xact.ConfigWriteResult).Rsp.Rc)
+}
+
+func (c *Client) StatList() ([]string, error) {
+	cmd := xact.NewStatListCmd()
+	cmd.SetTxOptions(c.TxOptions)
+
+	res, err := cmd.Run(c.Sesn)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := res.(*xact.StatListResult).Rsp
+	if err := checkRc("stat list", rsp.Rc); err != nil {
+		return nil, err
+	}
+
+	return rsp.List, nil
+}
+
+// Reads a statistics group.  Values are returned as decoded from the
+// response, typically as unsigned integers.
+func (c *Client) StatRead(name string) (map[string]interface{}, error) {
+	cmd := xact.NewStatReadCmd()
+	cmd.SetTxOptions(c.TxOptions)
+	cmd.Name = name
+
+	res, err := cmd.Run(c.Sesn)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := res.(*xact.StatReadResult).Rsp
+	if err := checkRc("stat read", rsp.Rc); err != nil {
+		return nil, err
+	}
+
+	return rsp.Fields, nil
+}
+
+// Reads the image state, including the split status.
+func (c *Client) ImageStateRead() (*nmp.ImageStateRsp, error) {
+	cmd := xact.NewImageStateReadCmd()
+	cmd.SetTxOptions(c.TxOptions)
+
+	res, err := cmd.Run(c.Sesn)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := res.(*xact.ImageStateReadResult).Rsp
+	if err := checkRc("image state read", rsp.Rc); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+func (c *Client) ImageState() ([]nmp.ImageStateEntry, error) {
+	rsp, err := c.ImageStateRead()
+	if err != nil {
+		return nil, err
+	}
+
+	return rsp.Images, nil
+}
+
+// Uploads an image to the secondary slot of the specified image.  progress
+// may be nil.
+func (c *Client) ImageUpload(data []byte, imageNum int,
+	progress ImageUploadProgressFn) error {
+
+	cmd := xact.NewImageUploadCmd()
+	cmd.SetTxOptions(c.TxOptions)
+	cmd.Data = data
+	cmd.ImageNum = imageNum
+	if progress != nil {
+		cmd.ProgressCb = func(_ *xact.ImageUploadCmd,
+			rsp *nmp.ImageUploadRsp) {
+
+			progress(int(rsp.Off), len(data))
+		}
+	}
+
+	res, err := cmd.Run(c.Sesn)
+	if err != nil {
+		return err
+	}
+
+	return checkRc("image upload", res.Status())
+}
+
+// Settings for an image upgrade; see ImageUpgrade.
+type ImageUpgradeOptions struct {
+	ImageNum int
+	Upgrade  bool
+	NoErase  bool
+	MaxWinSz int
+	ChunkSz  int
+	Resume   bool
+}
+
+// Uploads an image with the full set of upload options.  Unless NoErase is
+// set, the target slot is erased first.  progress may be nil.
+func (c *Client) ImageUpgrade(data []byte, opts ImageUpgradeOptions,
+	progress ImageUploadProgressFn) error {
+
+	cmd := xact.NewImageUpgradeCmd()
+	cmd.SetTxOptions(c.TxOptions)
+	cmd.Data = data
+	cmd.ImageNum = opts.ImageNum
+	cmd.Upgrade = opts.Upgrade
+	cmd.NoErase = opts.NoErase
+	cmd.MaxWinSz = opts.MaxWinSz
+	cmd.ChunkSz = opts.ChunkSz
+	cmd.Resume = opts.Resume
+	if progress != nil {
+		cmd.ProgressCb = func(_ *xact.ImageUploadCmd,
+			rsp *nmp.ImageUploadRsp) {
+
+			progress(int(rsp.Off), len(data))
+		}
+	}
+
+	res, err := cmd.Run(c.Sesn)
+	if err != nil {
+		return err
+	}
+
+	return checkRc("image upload", res.Status())
+}
+
+// Marks an image to be run on the next reset and returns the resulting image
+// state.  If confirm is true, the image is made permanent; otherwise it runs
+// once as a test.  A nil hash confirms the running image.
+func (c *Client) ImageStateWrite(hash []byte,
+	confirm bool) (*nmp.ImageStateRsp, error) {
+
+	cmd := xact.NewImageStateWriteCmd()
+	cmd.SetTxOptions(c.TxOptions)
+	cmd.Hash = hash
+	cmd.Confirm = confirm
+
+	res, err := cmd.Run(c.Sesn)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := res.(*xact.ImageStateWriteResult).Rsp
+	if err := checkRc("image state write", rsp.Rc); err != nil {
+		return nil, err
+	}
+
+	return rsp, nil
+}
+
+// Like ImageStateWrite, but discards the resulting state.
+func (c *Client) ImageSetState(hash []byte, confirm bool) error {
+	_, err := c.ImageStateWrite(hash, confirm)
+	return err
+}
+
+// Erases an image slot.  A negative slot selects the device's default, the
+// secondary slot of the first image; otherwise slots count across images
+// (image n's slots are 2n and 2n+1).
+func (c *Client) ImageErase(slot int) error {
+	cmd := xact.NewImageEraseCmd()
+	cmd.SetTxOptions(c.TxOptions)
+	cmd.Slot = slot
+
+	res, err := cmd.Run(c.Sesn)
+	if err != nil {
+		return err
+	}
+
+	return checkRc("image erase", res.Status())
+}
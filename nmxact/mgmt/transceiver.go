@@ -267,6 +267,8 @@ func (t *Transceiver) txRxOmpAsync(txCb TxFn, req *nmp.NmpMsg, mtu int,
 func (t *Transceiver) TxRxMgmt(txCb TxFn, req *nmp.NmpMsg, mtu int,
 	timeout time.Duration) (nmp.NmpRsp, error) {
 
+	nmp.TraceTx(req)
+
 	if t.nd != nil {
 		return t.txRxNmp(txCb, req, mtu, timeout)
 	} else {
@@ -277,6 +279,8 @@ func (t *Transceiver) TxRxMgmt(txCb TxFn, req *nmp.NmpMsg, mtu int,
 func (t *Transceiver) TxRxMgmtAsync(txCb TxFn, req *nmp.NmpMsg, mtu int,
 	timeout time.Duration, ch chan nmp.NmpRsp, errc chan error) error {
 
+	nmp.TraceTx(req)
+
 	if t.nd != nil {
 		return t.txRxNmpAsync(txCb, req, mtu, timeout, ch, errc)
 	} else {
@@ -225,6 +225,14 @@ func (s *NakedSesn) Open() error {
 	for i := 0; i < s.cfg.Ble.Central.ConnTries; i++ {
 		var retry bool
 
+		if i > 0 {
+			delay := nmxutil.RetryDelay(s.cfg.Ble.Central.ConnRetryDelay,
+				i-1)
+			log.Debugf("Retrying BLE connection in %s (attempt %d of %d)",
+				delay, i+1, s.cfg.Ble.Central.ConnTries)
+			time.Sleep(delay)
+		}
+
 		retry, err = s.openOnce()
 		if err != nil {
 			s.shutdown(err)
@@ -475,9 +483,11 @@ func (s *NakedSesn) openOnce() (bool, error) {
 
 		// An ENOTCONN error code implies the "conn_find" request failed
 		// because the connection dropped immediately after being established.
-		// If this happened, retry the connect procedure.
+		// If this happened, retry the connect procedure.  Other failures are
+		// only retried if the caller asked for it.
 		bhdErr := nmxutil.ToBleHost(err)
-		retry := bhdErr != nil && bhdErr.Status == ERR_CODE_ENOTCONN
+		retry := s.cfg.Ble.Central.ConnRetryAll ||
+			(bhdErr != nil && bhdErr.Status == ERR_CODE_ENOTCONN)
 		return retry, err
 	}
 
@@ -107,6 +107,8 @@ var rspCtorMap = map[Ogi]rspCtor{
 }
 
 func DecodeRspBody(hdr *NmpHdr, body []byte) (NmpRsp, error) {
+	traceMsg("RX", hdr, body)
+
 	cb := rspCtorMap[Ogi{hdr.Op, hdr.Group, hdr.Id}]
 	if cb == nil {
 		return nil, fmt.Errorf("Unrecognized NMP op+group+id: %d, %d, %d",
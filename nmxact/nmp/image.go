@@ -229,6 +229,7 @@ func (r *CoreEraseRsp) Msg() *NmpMsg { return MsgFromReq(r) }
 
 type ImageEraseReq struct {
 	NmpBase `codec:"-"`
+	Slot    *int `codec:"slot,omitempty"`
 }
 
 type ImageEraseRsp struct {
@@ -21,6 +21,7 @@ package nmp
 
 import (
 	"fmt"
+	"strings"
 )
 
 //////////////////////////////////////////////////////////////////////////////
@@ -96,6 +97,26 @@ func LogLevelToString(lm int) string {
 	return name
 }
 
+func LogModuleFromString(s string) (int, error) {
+	for k, v := range LogModuleNameMap {
+		if strings.EqualFold(s, v) {
+			return k, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown log module: %s", s)
+}
+
+func LogLevelFromString(s string) (int, error) {
+	for k, v := range LogLevelNameMap {
+		if strings.EqualFold(s, v) {
+			return k, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown log level: %s", s)
+}
+
 func LogTypeToString(lm int) string {
 	name := LogTypeNameMap[lm]
 	if name == "" {
@@ -0,0 +1,98 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package nmp
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/ugorji/go/codec"
+
+	"mynewt.apache.org/newtmgr/nmxact/nmxutil"
+)
+
+var traceMtx sync.Mutex
+
+var opNameMap = map[uint8]string{
+	NMP_OP_READ:      "read",
+	NMP_OP_READ_RSP:  "read_rsp",
+	NMP_OP_WRITE:     "write",
+	NMP_OP_WRITE_RSP: "write_rsp",
+}
+
+// Renders a CBOR payload as indented JSON.  If the payload cannot be decoded,
+// it is rendered as a hex dump instead.
+func tracePayloadText(body []byte) string {
+	val, err := nmxutil.DecodeCbor(body)
+	if err != nil {
+		return fmt.Sprintf("(%s)\n%s", err.Error(), hex.Dump(body))
+	}
+
+	h := new(codec.JsonHandle)
+	h.Indent = 4
+
+	var b []byte
+	if err := codec.NewEncoderBytes(&b, h).Encode(val); err != nil {
+		return hex.Dump(body)
+	}
+
+	return string(b) + "\n"
+}
+
+// Writes the header and decoded payload of a management packet to stderr if
+// protocol tracing is enabled.
+func traceMsg(dir string, hdr *NmpHdr, body []byte) {
+	if !nmxutil.DebugProtocol {
+		return
+	}
+
+	op := opNameMap[hdr.Op]
+	if op == "" {
+		op = "???"
+	}
+
+	traceMtx.Lock()
+	defer traceMtx.Unlock()
+
+	fmt.Fprintf(os.Stderr, "%s op=%s(%d) flags=0x%02x len=%d group=%d "+
+		"seq=%d id=%d\n",
+		dir, op, hdr.Op, hdr.Flags, hdr.Len, hdr.Group, hdr.Seq, hdr.Id)
+	fmt.Fprintf(os.Stderr, "%s", tracePayloadText(body))
+}
+
+// Traces an outgoing request.  The body is encoded separately from the
+// transmitted packet so that the trace is the same for both plain and OMP
+// framing.
+func TraceTx(nmr *NmpMsg) {
+	if !nmxutil.DebugProtocol {
+		return
+	}
+
+	bb, err := BodyBytes(nmr.Body)
+	if err != nil {
+		bb = nil
+	}
+
+	hdr := nmr.Hdr
+	hdr.Len = uint16(len(bb))
+	traceMsg("TX", &hdr, bb)
+}
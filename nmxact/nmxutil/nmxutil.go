@@ -39,6 +39,10 @@ import (
 const DURATION_FOREVER time.Duration = math.MaxInt64
 
 var Debug bool
+
+// Enables a stderr trace of every management request and response.
+var DebugProtocol bool
+
 var OmpRes string = "/omgr"
 
 var nextNmpSeq uint8
@@ -149,6 +153,24 @@ func EncodeCbor(value interface{}) ([]byte, error) {
 	return b, nil
 }
 
+// Upper bound on the delay between connection attempts.
+const MAX_RETRY_DELAY = 8 * time.Second
+
+// Calculates the delay before the specified retry (0 = first retry).  The
+// delay starts at base and doubles after each attempt, up to MAX_RETRY_DELAY.
+func RetryDelay(base time.Duration, retry int) time.Duration {
+	d := base
+	for i := 0; i < retry && d < MAX_RETRY_DELAY; i++ {
+		d *= 2
+	}
+
+	if d > MAX_RETRY_DELAY {
+		d = MAX_RETRY_DELAY
+	}
+
+	return d
+}
+
 func StopAndDrainTimer(timer *time.Timer) {
 	if !timer.Stop() {
 		<-timer.C
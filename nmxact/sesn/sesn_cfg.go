@@ -50,11 +50,20 @@ type OnCloseFn func(s Sesn, err error)
 type PeerSpec struct {
 	Ble bledefs.BleDev
 	Udp string
+	Tcp string
 }
 
 type SesnCfgBleCentral struct {
 	ConnTries   int
 	ConnTimeout time.Duration
+
+	// Delay before the first connection retry; doubles with each
+	// subsequent retry.
+	ConnRetryDelay time.Duration
+
+	// Retry after any connect failure, not just a connection that drops
+	// immediately after being established.
+	ConnRetryAll bool
 	// XXX: Missing fields.
 }
 
@@ -76,6 +85,18 @@ type SesnCfgLora struct {
 	Port        uint8
 }
 
+type SesnCfgUdp struct {
+	// Largest datagram to send, including all headers; 0 means the
+	// transport maximum.
+	Mtu int
+}
+
+type SesnCfgTcp struct {
+	// Largest packet to send, excluding the length prefix; 0 means the
+	// transport default.
+	Mtu int
+}
+
 type SesnCfg struct {
 	// General configuration.
 	MgmtProto MgmtProto
@@ -85,6 +106,8 @@ type SesnCfg struct {
 	// Transport-specific configuration.
 	Ble  SesnCfgBle
 	Lora SesnCfgLora
+	Udp  SesnCfgUdp
+	Tcp  SesnCfgTcp
 
 	// Filters
 	TxFilter nmcoap.TxMsgFilter
@@ -102,8 +125,9 @@ func NewSesnCfg() SesnCfg {
 			WriteRsp:     false,
 
 			Central: SesnCfgBleCentral{
-				ConnTries:   5,
-				ConnTimeout: 10 * time.Second,
+				ConnTries:      5,
+				ConnTimeout:    10 * time.Second,
+				ConnRetryDelay: 250 * time.Millisecond,
 			},
 		},
 		Lora: SesnCfgLora{
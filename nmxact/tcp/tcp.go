@@ -0,0 +1,87 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package tcp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Each packet on the stream is preceded by its length as a 16-bit big-endian
+// integer.
+const FRAME_HDR_SIZE = 2
+const MAX_PACKET_SIZE = 0xffff
+
+// Used when the connstring does not specify an MTU.  Most bridged devices
+// can't buffer a full-sized packet.
+const DEFAULT_MTU = 1024
+
+const DIAL_TIMEOUT = 10 * time.Second
+
+func Dial(peerString string, dispatchCb func(data []byte),
+	errCb func(err error)) (net.Conn, error) {
+
+	conn, err := net.DialTimeout("tcp", peerString, DIAL_TIMEOUT)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to %s: %s",
+			peerString, err.Error())
+	}
+
+	go func() {
+		r := bufio.NewReader(conn)
+		hdr := make([]byte, FRAME_HDR_SIZE)
+
+		for {
+			if _, err := io.ReadFull(r, hdr); err != nil {
+				errCb(err)
+				return
+			}
+
+			data := make([]byte, binary.BigEndian.Uint16(hdr))
+			if _, err := io.ReadFull(r, data); err != nil {
+				errCb(err)
+				return
+			}
+
+			log.Debugf("Received message from %v %d", conn.RemoteAddr(),
+				len(data))
+			dispatchCb(data)
+		}
+	}()
+
+	return conn, nil
+}
+
+// Prepends the length header to a packet.
+func Frame(data []byte) ([]byte, error) {
+	if len(data) > MAX_PACKET_SIZE {
+		return nil, fmt.Errorf("Packet too big for TCP frame: %d", len(data))
+	}
+
+	b := make([]byte, FRAME_HDR_SIZE, FRAME_HDR_SIZE+len(data))
+	binary.BigEndian.PutUint16(b, uint16(len(data)))
+	return append(b, data...), nil
+}
@@ -0,0 +1,208 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package tcp
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/runtimeco/go-coap"
+
+	"mynewt.apache.org/newtmgr/nmxact/mgmt"
+	"mynewt.apache.org/newtmgr/nmxact/nmcoap"
+	"mynewt.apache.org/newtmgr/nmxact/nmp"
+	"mynewt.apache.org/newtmgr/nmxact/nmxutil"
+	"mynewt.apache.org/newtmgr/nmxact/sesn"
+)
+
+type TcpSesn struct {
+	cfg  sesn.SesnCfg
+	conn net.Conn
+	txvr *mgmt.Transceiver
+
+	// Protects conn.
+	mtx sync.Mutex
+}
+
+func NewTcpSesn(cfg sesn.SesnCfg) (*TcpSesn, error) {
+	s := &TcpSesn{
+		cfg: cfg,
+	}
+	txvr, err := mgmt.NewTransceiver(cfg.TxFilter, cfg.RxFilter, false,
+		cfg.MgmtProto, 3)
+	if err != nil {
+		return nil, err
+	}
+	s.txvr = txvr
+
+	return s, nil
+}
+
+func (s *TcpSesn) Open() error {
+	if s.IsOpen() {
+		return nmxutil.NewSesnAlreadyOpenError(
+			"Attempt to open an already-open TCP session")
+	}
+
+	conn, err := Dial(s.cfg.PeerSpec.Tcp,
+		func(data []byte) {
+			s.txvr.DispatchNmpRsp(data)
+		},
+		func(err error) {
+			// Only report errors for connections the peer dropped; a local
+			// close has already failed any pending requests.
+			if s.IsOpen() {
+				s.txvr.ErrorAll(fmt.Errorf("TCP connection lost: %s",
+					err.Error()))
+			}
+		})
+	if err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	s.conn = conn
+	s.mtx.Unlock()
+
+	return nil
+}
+
+func (s *TcpSesn) Close() error {
+	s.mtx.Lock()
+	conn := s.conn
+	s.conn = nil
+	s.mtx.Unlock()
+
+	if conn == nil {
+		return nmxutil.NewSesnClosedError(
+			"Attempt to close an unopened TCP session")
+	}
+
+	conn.Close()
+	s.txvr.ErrorAll(fmt.Errorf("closed"))
+	s.txvr.Stop()
+	return nil
+}
+
+func (s *TcpSesn) IsOpen() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.conn != nil
+}
+
+func (s *TcpSesn) mtu() int {
+	if s.cfg.Tcp.Mtu > 0 && s.cfg.Tcp.Mtu < MAX_PACKET_SIZE {
+		return s.cfg.Tcp.Mtu
+	}
+	return DEFAULT_MTU
+}
+
+func (s *TcpSesn) MtuIn() int {
+	return MAX_PACKET_SIZE - nmp.NMP_HDR_SIZE
+}
+
+func (s *TcpSesn) MtuOut() int {
+	return s.mtu() - nmp.NMP_HDR_SIZE
+}
+
+// Writes a single framed packet to the stream.
+func (s *TcpSesn) txRaw(b []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.conn == nil {
+		return fmt.Errorf("Attempt to transmit over closed TCP session")
+	}
+
+	frame, err := Frame(b)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.Write(frame)
+	return err
+}
+
+func (s *TcpSesn) TxRxMgmt(m *nmp.NmpMsg,
+	timeout time.Duration) (nmp.NmpRsp, error) {
+
+	if !s.IsOpen() {
+		return nil, fmt.Errorf("Attempt to transmit over closed TCP session")
+	}
+
+	return s.txvr.TxRxMgmt(s.txRaw, m, s.mtu(), timeout)
+}
+
+func (s *TcpSesn) TxRxMgmtAsync(m *nmp.NmpMsg,
+	timeout time.Duration, ch chan nmp.NmpRsp, errc chan error) error {
+	rsp, err := s.TxRxMgmt(m, timeout)
+	if err != nil {
+		errc <- err
+	} else {
+		ch <- rsp
+	}
+	return nil
+}
+
+func (s *TcpSesn) AbortRx(seq uint8) error {
+	s.txvr.ErrorAll(fmt.Errorf("Rx aborted"))
+	return nil
+}
+
+func (s *TcpSesn) TxCoap(m coap.Message) error {
+	return s.txvr.TxCoap(s.txRaw, m, s.mtu())
+}
+
+func (s *TcpSesn) MgmtProto() sesn.MgmtProto {
+	return s.cfg.MgmtProto
+}
+
+func (s *TcpSesn) ListenCoap(mc nmcoap.MsgCriteria) (*nmcoap.Listener, error) {
+	return s.txvr.ListenCoap(mc)
+}
+
+func (s *TcpSesn) StopListenCoap(mc nmcoap.MsgCriteria) {
+	s.txvr.StopListenCoap(mc)
+}
+
+func (s *TcpSesn) CoapIsTcp() bool {
+	return false
+}
+
+func (s *TcpSesn) RxAccept() (sesn.Sesn, *sesn.SesnCfg, error) {
+	return nil, nil, fmt.Errorf("Op not implemented yet")
+}
+
+func (s *TcpSesn) RxCoap(opt sesn.TxOptions) (coap.Message, error) {
+	return nil, fmt.Errorf("Op not implemented yet")
+}
+
+func (s *TcpSesn) Filters() (nmcoap.TxMsgFilter, nmcoap.RxMsgFilter) {
+	return s.txvr.Filters()
+}
+
+func (s *TcpSesn) SetFilters(txFilter nmcoap.TxMsgFilter,
+	rxFilter nmcoap.RxMsgFilter) {
+
+	s.txvr.SetFilters(txFilter, rxFilter)
+}
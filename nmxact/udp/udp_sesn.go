@@ -99,7 +99,12 @@ func (s *UdpSesn) MtuIn() int {
 }
 
 func (s *UdpSesn) MtuOut() int {
-	return MAX_PACKET_SIZE -
+	mtu := MAX_PACKET_SIZE
+	if s.cfg.Udp.Mtu > 0 && s.cfg.Udp.Mtu < mtu {
+		mtu = s.cfg.Udp.Mtu
+	}
+
+	return mtu -
 		omp.OMP_MSG_OVERHEAD -
 		nmp.NMP_HDR_SIZE
 }
@@ -96,7 +96,7 @@ func (c *CrashCmd) Run(s sesn.Sesn) (Result, error) {
 	r := nmp.NewCrashReq()
 	r.CrashType = CrashTypeToString(c.CrashType)
 
-	rsp, err := txReq(s, r.Msg(), &c.CmdBase)
+	rsp, err := txReqOnce(s, r.Msg(), &c.CmdBase)
 	if err != nil {
 		return nil, err
 	}
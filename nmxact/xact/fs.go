@@ -172,6 +172,10 @@ func nextFsUploadReq(s sesn.Sesn, name string, data []byte, off int) (
 	oversize := len(enc) - s.MtuOut()
 	if oversize > 0 {
 		// Request too big.  Reduce the amount of file data.
+		if room-oversize <= 0 {
+			return nil, fmt.Errorf("Cannot create file upload request; " +
+				"MTU too low to fit any file data")
+		}
 		r = buildFsUploadReq(name, len(data), data[off:off+room-oversize], off)
 	}
 
@@ -56,6 +56,8 @@ type ImageUploadCmd struct {
 	ProgressCb ImageUploadProgressFn
 	ImageNum   int
 	MaxWinSz   int
+	ChunkSz    int
+	Resume     bool
 }
 
 type ImageUploadIntTracker struct {
@@ -128,11 +130,11 @@ func encodeUploadReq(s sesn.Sesn, hash []byte, upgrade bool, data []byte,
 }
 
 func findChunkLen(s sesn.Sesn, hash []byte, upgrade bool, data []byte,
-	off int, imageNum int, seq uint8) (int, error) {
+	off int, imageNum int, seq uint8, maxChunk int) (int, error) {
 
 	// Let's start by encoding max allowed chunk len and we will see how many
 	// bytes we need to cut
-	chunklen := min(len(data)-off, IMAGE_UPLOAD_MAX_CHUNK)
+	chunklen := min(len(data)-off, maxChunk)
 
 	// Keep reducing the chunk size until the request fits the MTU.
 	for {
@@ -148,13 +150,18 @@ func findChunkLen(s sesn.Sesn, hash []byte, upgrade bool, data []byte,
 		// Encoded length is larger than MTU, we need to make chunk shorter
 		overflow := len(enc) - s.MtuOut()
 		chunklen -= overflow
+		if chunklen <= 0 {
+			return 0, fmt.Errorf("Cannot create image upload request; "+
+				"MTU too low to fit any image data; max-payload-size=%d",
+				s.MtuOut())
+		}
 	}
 
 	return chunklen, nil
 }
 
-func nextImageUploadReq(s sesn.Sesn, upgrade bool, data []byte, off int, imageNum int) (
-	*nmp.ImageUploadReq, error) {
+func nextImageUploadReq(s sesn.Sesn, upgrade bool, data []byte, off int,
+	imageNum int, maxChunk int) (*nmp.ImageUploadReq, error) {
 	var hash []byte = nil
 
 	// Ensure we produce consistent requests while we calculate the chunk
@@ -174,7 +181,8 @@ func nextImageUploadReq(s sesn.Sesn, upgrade bool, data []byte, off int, imageNu
 	seq := nmxutil.NextNmpSeq()
 
 	// Find chunk length
-	chunklen, err := findChunkLen(s, hash, upgrade, data, off, imageNum, seq)
+	chunklen, err := findChunkLen(s, hash, upgrade, data, off, imageNum, seq,
+		maxChunk)
 	if err != nil {
 		return nil, err
 	}
@@ -183,7 +191,8 @@ func nextImageUploadReq(s sesn.Sesn, upgrade bool, data []byte, off int, imageNu
 	// fit we'll recalculate without hash
 	if off == 0 && chunklen < IMAGE_UPLOAD_MIN_1ST_CHUNK {
 		hash = nil
-		chunklen, err = findChunkLen(s, hash, upgrade, data, off, imageNum, seq)
+		chunklen, err = findChunkLen(s, hash, upgrade, data, off, imageNum, seq,
+			maxChunk)
 		if err != nil {
 			return nil, err
 		}
@@ -322,6 +331,57 @@ func (t *ImageUploadIntTracker) HandleError(off int, err error) bool {
 	}
 }
 
+// Retrieves the largest amount of image data to put in a single request.  The
+// chunk is further shortened as needed to fit the session's MTU.
+func (c *ImageUploadCmd) maxChunk() int {
+	if c.ChunkSz > 0 {
+		return c.ChunkSz
+	}
+	return IMAGE_UPLOAD_MAX_CHUNK
+}
+
+// Sends the first chunk of the image and waits for the response.  The first
+// chunk carries the SHA256 of the full image; a device that already holds a
+// partial upload with a matching hash replies with the offset it has
+// reached.  A device with no matching upload restarts and replies with the
+// offset following the first chunk.  Either way, the upload continues from
+// the offset the device asks for.
+func (c *ImageUploadCmd) resumeOff(s sesn.Sesn, res *ImageUploadResult) (
+	int, error) {
+
+	r, err := nextImageUploadReq(s, c.Upgrade, c.Data, 0, c.ImageNum,
+		c.maxChunk())
+	if err != nil {
+		return 0, err
+	}
+
+	rsp, err := txReq(s, r.Msg(), &c.CmdBase)
+	if err != nil {
+		return 0, err
+	}
+	irsp := rsp.(*nmp.ImageUploadRsp)
+
+	res.Rsps = append(res.Rsps, irsp)
+	if c.ProgressCb != nil {
+		c.ProgressCb(c, irsp)
+	}
+
+	if irsp.Rc != 0 {
+		return 0, nil
+	}
+
+	if int(irsp.Off) > len(c.Data) {
+		return 0, fmt.Errorf("Cannot resume image upload; device offset "+
+			"(%d) exceeds image size (%d)", irsp.Off, len(c.Data))
+	}
+
+	if irsp.Off > uint32(len(r.Data)) {
+		log.Debugf("Resuming image upload at offset %d", irsp.Off)
+	}
+
+	return int(irsp.Off), nil
+}
+
 func (c *ImageUploadCmd) Run(s sesn.Sesn) (Result, error) {
 	res := newImageUploadResult()
 	ch := make(chan int)
@@ -337,6 +397,19 @@ func (c *ImageUploadCmd) Run(s sesn.Sesn) (Result, error) {
 		MaxRxOff: 0,
 	}
 
+	if c.Resume && c.StartOff == 0 {
+		off, err := c.resumeOff(s, res)
+		if err != nil {
+			return nil, err
+		}
+		if res.Status() != 0 {
+			return res, nil
+		}
+
+		t.Off = off
+		t.MaxRxOff = int32(off)
+	}
+
 	for int(atomic.LoadInt32(&t.MaxRxOff)) < len(c.Data) {
 		// Block if window is full
 		if !t.CheckWindow() {
@@ -350,7 +423,8 @@ func (c *ImageUploadCmd) Run(s sesn.Sesn) (Result, error) {
 		}
 
 		t.Mutex.Lock()
-		r, err := nextImageUploadReq(s, c.Upgrade, c.Data, t.Off, c.ImageNum)
+		r, err := nextImageUploadReq(s, c.Upgrade, c.Data, t.Off, c.ImageNum,
+			c.maxChunk())
 		if err != nil {
 			t.Mutex.Unlock()
 			return nil, err
@@ -424,6 +498,8 @@ type ImageUpgradeCmd struct {
 	ProgressBar *pb.ProgressBar
 	ImageNum    int
 	MaxWinSz    int
+	ChunkSz     int
+	Resume      bool
 }
 
 type ImageUpgradeResult struct {
@@ -506,6 +582,8 @@ func (c *ImageUpgradeCmd) runUpload(s sesn.Sesn) (*ImageUploadResult, error) {
 		cmd.ImageNum = c.ImageNum
 		cmd.SetTxOptions(opt)
 		cmd.MaxWinSz = c.MaxWinSz
+		cmd.ChunkSz = c.ChunkSz
+		cmd.Resume = c.Resume
 
 		res, err := cmd.Run(s)
 		if err == nil {
@@ -674,6 +752,10 @@ func (c *CoreListCmd) Run(s sesn.Sesn) (Result, error) {
 
 type ImageEraseCmd struct {
 	CmdBase
+
+	// The slot to erase; if negative, the device chooses (normally the
+	// secondary slot).
+	Slot int
 }
 
 type ImageEraseResult struct {
@@ -683,6 +765,7 @@ type ImageEraseResult struct {
 func NewImageEraseCmd() *ImageEraseCmd {
 	return &ImageEraseCmd{
 		CmdBase: NewCmdBase(),
+		Slot:    -1,
 	}
 }
 
@@ -696,6 +779,10 @@ func (r *ImageEraseResult) Status() int {
 
 func (c *ImageEraseCmd) Run(s sesn.Sesn) (Result, error) {
 	r := nmp.NewImageEraseReq()
+	if c.Slot >= 0 {
+		slot := c.Slot
+		r.Slot = &slot
+	}
 
 	rsp, err := txReq(s, r.Msg(), &c.CmdBase)
 	if err != nil {
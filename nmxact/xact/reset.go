@@ -50,7 +50,7 @@ func (r *ResetResult) Status() int {
 func (c *ResetCmd) Run(s sesn.Sesn) (Result, error) {
 	r := nmp.NewResetReq()
 
-	rsp, err := txReq(s, r.Msg(), &c.CmdBase)
+	rsp, err := txReqOnce(s, r.Msg(), &c.CmdBase)
 	if err != nil {
 		return nil, err
 	}
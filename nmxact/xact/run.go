@@ -57,7 +57,7 @@ func (c *RunTestCmd) Run(s sesn.Sesn) (Result, error) {
 	r.Testname = c.Testname
 	r.Token = c.Token
 
-	rsp, err := txReq(s, r.Msg(), &c.CmdBase)
+	rsp, err := txReqOnce(s, r.Msg(), &c.CmdBase)
 	if err != nil {
 		return nil, err
 	}
@@ -28,6 +28,24 @@ import (
 func txReq(s sesn.Sesn, m *nmp.NmpMsg, c *CmdBase) (
 	nmp.NmpRsp, error) {
 
+	return txReqOpt(s, m, c, c.TxOptions())
+}
+
+// Transmits a request that must not be applied twice, such as a reset.  A
+// lost response cannot be distinguished from a lost request, so the request is
+// sent exactly once regardless of the configured number of tries.
+func txReqOnce(s sesn.Sesn, m *nmp.NmpMsg, c *CmdBase) (
+	nmp.NmpRsp, error) {
+
+	opt := c.TxOptions()
+	opt.Tries = 1
+
+	return txReqOpt(s, m, c, opt)
+}
+
+func txReqOpt(s sesn.Sesn, m *nmp.NmpMsg, c *CmdBase, opt sesn.TxOptions) (
+	nmp.NmpRsp, error) {
+
 	if c.abortErr != nil {
 		return nil, c.abortErr
 	}
@@ -39,7 +57,7 @@ func txReq(s sesn.Sesn, m *nmp.NmpMsg, c *CmdBase) (
 		c.curSesn = nil
 	}()
 
-	rsp, err := sesn.TxRxMgmt(s, m, c.TxOptions())
+	rsp, err := sesn.TxRxMgmt(s, m, opt)
 	if err != nil {
 		return nil, err
 	}